@@ -1,13 +1,25 @@
 package koala
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/rs/cors"
 	"github.com/tralus/koala/config"
+	"github.com/tralus/koala/health"
 	"github.com/tralus/koala/knife"
+	"github.com/tralus/koala/logger"
+	"github.com/tralus/koala/migration"
 )
 
 // Config holds the app config
@@ -28,16 +40,41 @@ func init() {
 	}
 }
 
-// Module defines the interface for modules.
-type Module interface {
-	Up()
-}
+// DefaultShutdownTimeout is the deadline to drain connections on shutdown.
+// The modules are then stopped with a deadline of the same duration.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Health endpoints paths
+const (
+	HealthPath = "/healthz"
+	ReadyPath  = "/readyz"
+)
+
+// Admin endpoints paths
+const (
+	MetricsPath = "/metrics"
+	RoutesPath  = "/routes"
+	PprofPath   = "/debug/pprof/"
+)
+
+// DefaultServerPort is the server port when none is configured
+const DefaultServerPort = 9003
 
 // App represents a Koala Application
 type App struct {
 	cors    *cors.Cors
 	router  *knife.Router
 	modules []Module
+	started []Module
+	report  StartupReport
+	health  *health.Registry
+
+	commands  []Command
+	migration *migration.Migration
+	out       io.Writer
+	logger    *logger.Logger
+
+	shutdownTimeout time.Duration
 }
 
 // NewApplication creates an instance of App
@@ -55,6 +92,12 @@ func (a *App) SetCors(c *cors.Cors) {
 	a.cors = c
 }
 
+// SetShutdownTimeout sets the deadline to drain connections on shutdown.
+// It overrides the Server.ShutdownTimeout setting.
+func (a *App) SetShutdownTimeout(d time.Duration) {
+	a.shutdownTimeout = d
+}
+
 // AddModules adds the modules for the application
 func (a *App) AddModules(m []Module) {
 	for _, z := range m {
@@ -67,51 +110,386 @@ func (a *App) AddModule(m Module) {
 	a.modules = append(a.modules, m)
 }
 
-// Gets the separator for the output
-func sep() string {
-	return "***"
+// SetLogger sets the application logger.
+// The router uses it when it has no logger.
+func (a *App) SetLogger(l *logger.Logger) {
+	a.logger = l
+}
+
+// Logger gets the application logger.
+// By default, it is chosen by the Debug setting.
+func (a *App) Logger() *logger.Logger {
+	if a.logger == nil {
+		a.logger = logger.NewDefault(Config.Debug)
+	}
+
+	return a.logger
 }
 
-// Run starts the application
+// Run starts the application.
+// The server listens while the modules start, so the health endpoints
+// answer 503 and the router is not reached until the app is ready.
 func (a *App) Run() error {
 	// The router can not be nil
 	if a.router == nil {
 		panic("Set a not nil router to run.")
 	}
 
-	fmt.Println("Starting app...")
+	log := a.Logger()
+
+	log.Info("Starting app...")
+
+	sc := serverConfig(Config.Server)
+
+	ServerPort = strconv.Itoa(sc.Port)
 
-	// Up application modules
-	for _, m := range a.modules {
-		m.Up()
+	scheme := "http"
+
+	if sc.HasTLS() {
+		scheme = "https"
+	}
+
+	host := sc.Host
+
+	if len(host) == 0 {
+		host = "localhost"
 	}
 
-	ServerPort := os.Getenv("PORT")
+	log.Info("Listening",
+		"debug", Config.Debug,
+		"port", ServerPort,
+		"url", fmt.Sprintf("%s://%s:%s", scheme, host, ServerPort))
 
-	if len(ServerPort) == 0 {
-		ServerPort = "9003"
+	if a.router.Logger() == nil {
+		a.router.SetLogger(log)
 	}
 
-	fmt.Println(sep())
-	fmt.Printf("Debug: %t\n", Config.Debug)
-	fmt.Printf("Port: %s\n", ServerPort)
+	if dir := Config.Templates.Dir; dir != "" && a.router.Renderer() == nil {
+		a.router.SetRenderer(knife.NewRenderer(dir, Config.Debug))
+	}
 
-	fmt.Println(sep())
-	fmt.Printf("On http://localhost:%s\n", ServerPort)
-	fmt.Println("To shut down, press <CTRL> + C.")
+	if c := Config.OpenAPI; c.Path != "" {
+		a.router.SetOpenAPI(c.Path, knife.OpenAPIInfo{Title: c.Title, Version: c.Version})
+	}
 
 	// Starts the router
-	handler := a.router.Start()
+	var handler http.Handler = a.router.Start()
 
 	if a.cors != nil {
-		// Starts the server with CORS support
-		return http.ListenAndServe(
-			fmt.Sprintf(":%s", ServerPort),
-			a.cors.Handler(handler),
-		)
+		// Adds CORS support
+		handler = a.cors.Handler(handler)
+	}
+
+	servers := []server{{newServer(sc, a.handler(handler)), sc}}
+
+	if ac := Config.Admin; ac.Port != 0 {
+		log.Info("Admin listening", "addr", ac.Addr())
+		servers = append(servers, server{newServer(ac, a.adminHandler()), ac})
+	}
+
+	return a.serve(servers)
+}
+
+// Health gets the health registry of the application
+func (a *App) Health() *health.Registry {
+	if a.health == nil {
+		a.health = health.NewRegistry()
+	}
+
+	return a.health
+}
+
+// AddHealthCheck registers a named health checker
+func (a *App) AddHealthCheck(name string, c health.Checker) {
+	a.Health().Register(name, c)
+}
+
+// handler mounts the health endpoints outside the router.
+// The router answers 503 while the app is starting, and keeps serving
+// while it drains, so only the readiness reports it.
+func (a *App) handler(h http.Handler) http.Handler {
+	hr := a.Health()
+
+	mux := http.NewServeMux()
+
+	mux.Handle(HealthPath, hr.LivenessHandler())
+	mux.Handle(ReadyPath, hr.ReadinessHandler())
+
+	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hr.State() == health.Starting {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable),
+				http.StatusServiceUnavailable)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	}))
+
+	return mux
+}
+
+// adminHandler serves pprof, route metrics, health checks and the route table
+func (a *App) adminHandler() http.Handler {
+	hr := a.Health()
+
+	mux := http.NewServeMux()
+
+	mux.Handle(HealthPath, hr.LivenessHandler())
+	mux.Handle(ReadyPath, hr.ReportHandler())
+	mux.Handle(MetricsPath, a.router.Metrics())
+	mux.Handle(RoutesPath, a.router.RouteTableHandler())
+
+	mux.HandleFunc(PprofPath, pprof.Index)
+	mux.HandleFunc(PprofPath+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(PprofPath+"profile", pprof.Profile)
+	mux.HandleFunc(PprofPath+"symbol", pprof.Symbol)
+	mux.HandleFunc(PprofPath+"trace", pprof.Trace)
+
+	return mux
+}
+
+// serverConfig fills the server settings with the defaults.
+// The PORT env var takes precedence over the configured port.
+func serverConfig(sc config.Server) config.Server {
+	if p, err := strconv.Atoi(os.Getenv("PORT")); err == nil {
+		sc.Port = p
 	}
 
-	// Starts the server
-	return http.ListenAndServe(
-		fmt.Sprintf(":%s", ServerPort), handler)
+	if sc.Port == 0 {
+		sc.Port = DefaultServerPort
+	}
+
+	return sc
+}
+
+// server represents a http server and its settings
+type server struct {
+	*http.Server
+
+	config config.Server
+}
+
+// listen opens the listener of the server and loads the TLS files,
+// so their errors are reported before the modules start
+func (s server) listen() (net.Listener, error) {
+	if s.config.HasTLS() {
+		cert, err := tls.LoadX509KeyPair(s.config.TLS.CertFile, s.config.TLS.KeyFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		s.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	return net.Listen("tcp", s.Addr)
+}
+
+// serve serves the requests of the listener.
+// It uses HTTPS when the TLS files are set,
+// so HTTP/2 is negotiated by the net/http package.
+func (s server) serve(ln net.Listener) error {
+	if s.config.HasTLS() {
+		return s.ServeTLS(ln, "", "")
+	}
+
+	return s.Serve(ln)
+}
+
+// newServer creates a *http.Server from the server settings
+func newServer(sc config.Server, h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              sc.Addr(),
+		Handler:           h,
+		ReadTimeout:       sc.ReadTimeout,
+		ReadHeaderTimeout: sc.ReadHeaderTimeout,
+		WriteTimeout:      sc.WriteTimeout,
+		IdleTimeout:       sc.IdleTimeout,
+		MaxHeaderBytes:    sc.MaxHeaderBytes,
+	}
+}
+
+// serve opens the listeners, starts the servers and the modules,
+// and waits for SIGINT or SIGTERM. A listener that can not be opened
+// fails the start before the modules run.
+// On signal, it drains the connections of all servers and stops the modules.
+func (a *App) serve(servers []server) error {
+	hr := a.Health()
+	hr.SetState(health.Starting)
+
+	listeners := make([]net.Listener, 0, len(servers))
+
+	for _, s := range servers {
+		ln, err := s.listen()
+
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return err
+		}
+
+		listeners = append(listeners, ln)
+	}
+
+	errs := make(chan error, len(servers))
+
+	for i, s := range servers {
+		go func(s server, ln net.Listener) {
+			errs <- s.serve(ln)
+		}(s, listeners[i])
+	}
+
+	closeAll := func() {
+		for _, s := range servers {
+			s.Close()
+		}
+	}
+
+	log := a.Logger()
+
+	// Starts application modules
+	report, err := a.startModules()
+
+	for _, s := range report {
+		if s.Err != nil {
+			log.Error("Module failed", "module", s.Name, "phase", s.Phase, "duration", s.Duration, "error", s.Err)
+		} else {
+			log.Info("Module ok", "module", s.Name, "phase", s.Phase, "duration", s.Duration)
+		}
+	}
+
+	if err != nil {
+		closeAll()
+		return err
+	}
+
+	log.Info("App ready")
+
+	hr.SetState(health.Ready)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	select {
+	case err := <-errs:
+		log.Error("Server failed", "error", err)
+		hr.SetState(health.Draining)
+		closeAll()
+		a.stopModules(context.Background())
+		return err
+	case sig := <-stop:
+		log.Info("Shutting down...", "signal", sig)
+	}
+
+	hr.SetState(health.Draining)
+
+	timeout := a.shutdownTimeout
+
+	if timeout == 0 {
+		timeout = servers[0].config.ShutdownTimeout
+	}
+
+	if timeout == 0 {
+		timeout = DefaultShutdownTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for _, s := range servers {
+		if errShutdown := s.Shutdown(ctx); err == nil {
+			err = errShutdown
+		}
+	}
+
+	// The modules get their own deadline, so a long drain does not
+	// leave them an expired context to close their resources
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), timeout)
+	defer stopCancel()
+
+	if errStop := a.stopModules(stopCtx); err == nil {
+		err = errStop
+	}
+
+	if err != nil {
+		log.Error("Shutdown failed", "error", err)
+	} else {
+		log.Info("Shutdown complete")
+	}
+
+	return err
+}
+
+// Report gets the startup report of the modules
+func (a *App) Report() StartupReport {
+	return a.report
+}
+
+// startModules initializes and starts the modules in dependency order.
+// When a module fails, the modules already started are stopped.
+func (a *App) startModules() (StartupReport, error) {
+	a.report = nil
+
+	modules, err := sortModules(a.modules)
+
+	if err != nil {
+		return nil, err
+	}
+
+	run := func(m Module, phase string, f func() error) error {
+		begin := time.Now()
+		err := f()
+
+		a.report = append(a.report, ModuleStatus{m.Name(), phase, time.Since(begin), err})
+
+		if err != nil {
+			return NewModuleError(m.Name(), phase, err)
+		}
+
+		return nil
+	}
+
+	for _, m := range modules {
+		if err := run(m, PhaseInit, m.Init); err != nil {
+			return a.report, err
+		}
+
+		if hm, ok := m.(HealthModule); ok {
+			for name, c := range hm.HealthChecks() {
+				a.AddHealthCheck(name, c)
+			}
+		}
+	}
+
+	for _, m := range modules {
+		if err := run(m, PhaseStart, m.Start); err != nil {
+			a.stopModules(context.Background())
+			return a.report, err
+		}
+
+		a.started = append(a.started, m)
+	}
+
+	return a.report, nil
+}
+
+// stopModules stops the started modules in reverse order of Start.
+// It returns the first error, but all modules are stopped.
+func (a *App) stopModules(ctx context.Context) error {
+	var first error
+
+	for i := len(a.started) - 1; i >= 0; i-- {
+		m := a.started[i]
+
+		if err := m.Stop(ctx); err != nil && first == nil {
+			first = NewModuleError(m.Name(), PhaseStop, err)
+		}
+	}
+
+	a.started = nil
+
+	return first
 }
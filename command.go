@@ -0,0 +1,291 @@
+package koala
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tralus/koala/config"
+	"github.com/tralus/koala/errors"
+	"github.com/tralus/koala/migration"
+)
+
+// DefaultCommand is the command executed when none is given
+const DefaultCommand = "serve"
+
+// Command represents an application command.
+// Run receives the arguments after the command name.
+type Command struct {
+	Name  string
+	Usage string
+	Run   func(args []string) error
+}
+
+// CommandModule defines the optional interface for modules
+// that register their own commands.
+type CommandModule interface {
+	Commands() []Command
+}
+
+// AddCommand adds a command for the application
+func (a *App) AddCommand(c Command) {
+	a.commands = append(a.commands, c)
+}
+
+// SetMigration sets the migration used by the migrate command
+func (a *App) SetMigration(m migration.Migration) {
+	a.migration = &m
+}
+
+// SetOutput sets the output of the commands
+func (a *App) SetOutput(w io.Writer) {
+	a.out = w
+}
+
+// output gets the output of the commands
+func (a *App) output() io.Writer {
+	if a.out == nil {
+		return os.Stdout
+	}
+
+	return a.out
+}
+
+// Main executes the command from the program arguments.
+// It exits with status 1 when the command fails.
+func (a *App) Main() {
+	if err := a.Exec(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Exec executes the command named by the first argument.
+// The serve command is executed when args is empty.
+func (a *App) Exec(args []string) error {
+	name := DefaultCommand
+
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	commands, err := a.allCommands()
+
+	if err != nil {
+		return err
+	}
+
+	for _, c := range commands {
+		if c.Name == name {
+			return c.Run(args)
+		}
+	}
+
+	a.usage(commands)
+
+	err = errors.Errorf("Unknown command %s.", name)
+	return errors.NewIllegalArgumentError(err)
+}
+
+// allCommands gets the built-in, the application and the modules commands
+func (a *App) allCommands() ([]Command, error) {
+	commands := []Command{
+		{"serve", "starts the application server", func([]string) error { return a.Run() }},
+		{"migrate", "migrate up|down|status runs or lists the migrations", a.migrate},
+		{"routes", "prints the route table", a.printRoutes},
+		{"config", "config print prints the application settings", a.printConfig},
+	}
+
+	commands = append(commands, a.commands...)
+
+	for _, m := range a.modules {
+		if cm, ok := m.(CommandModule); ok {
+			commands = append(commands, cm.Commands()...)
+		}
+	}
+
+	names := make(map[string]bool, len(commands))
+
+	for _, c := range commands {
+		if names[c.Name] {
+			err := errors.Errorf("Command %s is registered many times.", c.Name)
+			return nil, errors.NewIllegalStateError(err)
+		}
+
+		names[c.Name] = true
+	}
+
+	commands = append(commands, Command{"help", "prints the commands", func([]string) error {
+		a.usage(commands)
+		return nil
+	}})
+
+	return commands, nil
+}
+
+// usage prints the commands sorted by name
+func (a *App) usage(commands []Command) {
+	sorted := append([]Command{}, commands...)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	w := tabwriter.NewWriter(a.output(), 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Usage: %s <command> [args]\n\n", os.Args[0])
+
+	for _, c := range sorted {
+		fmt.Fprintf(w, "  %s\t%s\n", c.Name, c.Usage)
+	}
+
+	w.Flush()
+}
+
+// migrate runs the migrate command
+func (a *App) migrate(args []string) error {
+	if a.migration == nil {
+		err := errors.New("Set a migration to run the migrate command.")
+		return errors.NewIllegalStateError(err)
+	}
+
+	if len(args) != 1 {
+		err := errors.New("Usage: migrate up|down|status.")
+		return errors.NewIllegalArgumentError(err)
+	}
+
+	out := a.output()
+
+	switch args[0] {
+	case "up":
+		n, err := a.migration.Up()
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Number of migrations applied: %d.\n", n)
+
+	case "down":
+		n, err := a.migration.Down()
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Number of migrations reverted: %d.\n", n)
+
+	case "status":
+		status, err := a.migration.Status()
+
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+		fmt.Fprintln(w, "MIGRATION\tAPPLIED AT")
+
+		for _, s := range status {
+			at := "pending"
+
+			if s.Applied {
+				at = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Fprintf(w, "%s\t%s\n", s.ID, at)
+		}
+
+		w.Flush()
+
+	default:
+		err := errors.Errorf("Unknown migrate command %s.", args[0])
+		return errors.NewIllegalArgumentError(err)
+	}
+
+	return nil
+}
+
+// printRoutes prints the route table with the middlewares chain
+func (a *App) printRoutes(args []string) error {
+	if a.router == nil {
+		return errors.NewIllegalStateError(errors.New("Set a router to print the routes."))
+	}
+
+	w := tabwriter.NewWriter(a.output(), 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, "TOKEN\tMETHOD\tPATH\tMIDDLEWARES")
+
+	for _, r := range a.router.RouteTable() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			r.Token, r.Method, r.Path, strings.Join(r.Middlewares, " > "))
+	}
+
+	return w.Flush()
+}
+
+// printConfig prints the application settings as JSON.
+// The secrets and the DSN password are masked.
+func (a *App) printConfig(args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		err := errors.New("Usage: config print.")
+		return errors.NewIllegalArgumentError(err)
+	}
+
+	bytes, err := json.MarshalIndent(maskConfig(Config), "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(a.output(), string(bytes))
+
+	return err
+}
+
+// maskConfig masks the secrets of the settings
+func maskConfig(c config.Config) config.Config {
+	const mask = "******"
+
+	if c.Session.Secret != "" {
+		c.Session.Secret = mask
+	}
+
+	if c.Jwt.Secret != "" {
+		c.Jwt.Secret = mask
+	}
+
+	c.DB.DSN = maskDSN(c.DB.DSN, mask)
+
+	return c
+}
+
+// dsnPasswordRegex matches the password of the key=value DSNs,
+// like "host=db password=secret"
+var dsnPasswordRegex = regexp.MustCompile(`(?i)\b(password|pwd)\s*=\s*('[^']*'|[^\s;]*)`)
+
+// dsnUserRegex matches the password of the MySQL DSNs,
+// like "user:secret@tcp(db:3306)/app"
+var dsnUserRegex = regexp.MustCompile(`^([^:@/]*):[^@/]*@`)
+
+// maskDSN masks the password of the URL, key=value and MySQL DSNs
+func maskDSN(dsn, mask string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Host != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), mask)
+			return u.String()
+		}
+
+		return dsn
+	}
+
+	dsn = dsnPasswordRegex.ReplaceAllString(dsn, "${1}="+mask)
+
+	return dsnUserRegex.ReplaceAllString(dsn, "${1}:"+mask+"@")
+}
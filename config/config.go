@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -10,6 +11,10 @@ import (
 // ConfigFilename settings filename
 var ConfigFilename string
 
+// DefaultAdminHost is the host of the admin server when it is not set,
+// so pprof, the metrics and the routes are only served locally.
+const DefaultAdminHost = "127.0.0.1"
+
 func init() {
 	// Gets the config filename from env var
 	ConfigFilename = os.Getenv("CONFIG_FILENAME")
@@ -23,6 +28,9 @@ func init() {
 	viper.AddConfigPath("./config")
 	viper.AutomaticEnv()
 
+	// The admin server is private by default
+	viper.SetDefault("admin.host", DefaultAdminHost)
+
 	// Find and read the config file
 	err := viper.ReadInConfig()
 
@@ -35,6 +43,25 @@ func init() {
 type Config struct {
 	Debug bool
 
+	Server Server
+
+	// The admin server is enabled when its port is set.
+	// It listens on DefaultAdminHost unless its host is set,
+	// like "0.0.0.0" to listen on all interfaces.
+	Admin Server
+
+	// The templates are rendered when their directory is set
+	Templates struct {
+		Dir string
+	}
+
+	// The OpenAPI document is served when its path is set
+	OpenAPI struct {
+		Path    string
+		Title   string
+		Version string
+	}
+
 	Session struct {
 		Secret string
 	}
@@ -50,6 +77,37 @@ type Config struct {
 	}
 }
 
+// Server represents the http server settings.
+// The durations are parsed from strings like "5s" or "1m".
+type Server struct {
+	Host string
+	Port int
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+
+	MaxHeaderBytes int
+
+	// The server uses HTTPS and HTTP/2 when both files are set
+	TLS struct {
+		CertFile string
+		KeyFile  string
+	}
+}
+
+// Addr gets the server address in the form "host:port"
+func (s Server) Addr() string {
+	return fmt.Sprintf("%s:%d", s.Host, s.Port)
+}
+
+// HasTLS verifies if the TLS cert and key files are set
+func (s Server) HasTLS() bool {
+	return s.TLS.CertFile != "" && s.TLS.KeyFile != ""
+}
+
 // Viper returns the viper instance
 func (c Config) Viper() *viper.Viper {
 	return viper.GetViper()
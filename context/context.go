@@ -1,25 +1,67 @@
+// Package context stores request-scoped values on http.Request.Context().
+// The keys should be unexported typed values to avoid collisions.
 package context
 
 import (
+	stdcontext "context"
 	"net/http"
+	"regexp"
 
-	"github.com/gorilla/context"
 	"github.com/tralus/koala/errors"
 )
 
-// Add adds a value to the context
+// With creates a shallow copy of r with the value added to its context.
+// The returned request must be passed to the next handlers.
+func With(r *http.Request, key, val interface{}) *http.Request {
+	return r.WithContext(stdcontext.WithValue(r.Context(), key, val))
+}
+
+// Add adds a value to the context of r, replacing the request in place.
+//
+// Deprecated: use With and pass the returned request to the next handlers.
+// The handlers holding a copy of r do not see the value.
 func Add(r *http.Request, key, val interface{}) {
-	context.Set(r, key, val)
+	*r = *With(r, key, val)
 }
 
 // Get gets a value from the context
 func Get(r *http.Request, key interface{}) (interface{}, error) {
-	u := context.Get(r, key)
+	u := r.Context().Value(key)
 
 	if u == nil {
-		err := errors.Errorf("Key %s is not in the context.", key)
+		err := errors.Errorf("Key %v is not in the context.", key)
 		return nil, errors.NewIllegalStateError(err)
 	}
 
 	return u, nil
 }
+
+// contextKey is the type of the context keys of this package
+type contextKey string
+
+const keyRequestID contextKey = "koala.request_id"
+
+// requestIDRegex validates the request ids
+var requestIDRegex = regexp.MustCompile(`^[\w.-]{1,128}$`)
+
+// ValidRequestID verifies if the request id is safe to be used
+// on the headers, the logs and the SQL comments
+func ValidRequestID(id string) bool {
+	return requestIDRegex.MatchString(id)
+}
+
+// WithRequestID creates a copy of ctx holding the request id.
+// Invalid ids are ignored.
+func WithRequestID(ctx stdcontext.Context, id string) stdcontext.Context {
+	if !ValidRequestID(id) {
+		return ctx
+	}
+
+	return stdcontext.WithValue(ctx, keyRequestID, id)
+}
+
+// RequestID gets the request id from ctx, or an empty string
+func RequestID(ctx stdcontext.Context) string {
+	id, _ := ctx.Value(keyRequestID).(string)
+	return id
+}
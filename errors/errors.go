@@ -19,6 +19,11 @@ func (e BaseError) Error() string {
 	return fmt.Sprintf("%s", e.err)
 }
 
+// Origin gets the wrapped error
+func (e BaseError) Origin() error {
+	return e.err
+}
+
 // GetStack implements RootError
 func (e BaseError) GetStack() string {
 	return fmt.Sprintf("%+v\n", e)
@@ -90,10 +95,22 @@ func NewNotFoundError(err error) error {
 	return NotFoundError{NewBaseError(err)}
 }
 
-// IsNotFoundError verifies if error is a NotFoundError
+// NotFounder is implemented by the errors of other packages
+// reporting a missing resource, like sqlxtpl.EmptyResultDataError
+type NotFounder interface {
+	NotFound() bool
+}
+
+// IsNotFoundError verifies if error is a NotFoundError or a NotFounder
 func IsNotFoundError(err error) bool {
-	_, ok := errors.Cause(err).(NotFoundError)
-	return ok
+	switch e := errors.Cause(err).(type) {
+	case NotFoundError:
+		return true
+	case NotFounder:
+		return e.NotFound()
+	}
+
+	return false
 }
 
 // IllegalStateError represents an illegal state error
@@ -124,9 +141,9 @@ func NewIllegalArgumentError(err error) error {
 	return IllegalArgumentError{NewBaseError(err)}
 }
 
-// IsIllegalArgumentError verifies if error is an IllegalStateError
+// IsIllegalArgumentError verifies if error is an IllegalArgumentError
 func IsIllegalArgumentError(err error) bool {
-	_, ok := errors.Cause(err).(IllegalStateError)
+	_, ok := errors.Cause(err).(IllegalArgumentError)
 	return ok
 }
 
@@ -161,3 +178,19 @@ func IsNotAuthorizedError(err error) bool {
 	_, ok := errors.Cause(err).(NotAuthorizedError)
 	return ok
 }
+
+// ForbiddenError represents the error for an authenticated user without permission
+type ForbiddenError struct {
+	BaseError
+}
+
+// NewForbiddenError creates a ForbiddenError instance
+func NewForbiddenError(err error) error {
+	return ForbiddenError{NewBaseError(err)}
+}
+
+// IsForbiddenError verifies if error is a ForbiddenError
+func IsForbiddenError(err error) bool {
+	_, ok := errors.Cause(err).(ForbiddenError)
+	return ok
+}
@@ -0,0 +1,254 @@
+// Package health provides named health checks and the
+// liveness and readiness endpoints for the application.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout is the deadline for each check
+const DefaultTimeout = 5 * time.Second
+
+// Check statuses
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// State represents the application state
+type State int32
+
+// Application states
+const (
+	Starting State = iota
+	Ready
+	Draining
+)
+
+// String gets the state name
+func (s State) String() string {
+	switch s {
+	case Starting:
+		return "starting"
+	case Ready:
+		return "ready"
+	case Draining:
+		return "draining"
+	}
+
+	return "unknown"
+}
+
+// Checker defines the interface for health checks
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc represents a checker created from a function
+type CheckerFunc func(ctx context.Context) error
+
+// Check implements Checker
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Pinger defines the interface for connections that can be pinged.
+// It is implemented by *sql.DB and *sqlx.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingChecker creates a checker that pings p
+func PingChecker(p Pinger) Checker {
+	return CheckerFunc(p.PingContext)
+}
+
+// Result represents the result of a check
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report represents the result of all checks
+type Report struct {
+	Status string   `json:"status"`
+	State  string   `json:"state"`
+	Checks []Result `json:"checks"`
+}
+
+// Registry holds the named checkers and the application state
+type Registry struct {
+	mu       sync.RWMutex
+	checkers map[string]Checker
+	state    int32
+	timeout  time.Duration
+}
+
+// NewRegistry creates a *Registry instance in the Starting state
+func NewRegistry() *Registry {
+	return &Registry{
+		checkers: make(map[string]Checker),
+		timeout:  DefaultTimeout,
+	}
+}
+
+// Register registers a named checker.
+// A checker with the same name is replaced.
+func (r *Registry) Register(name string, c Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checkers[name] = c
+}
+
+// SetTimeout sets the deadline for each check
+func (r *Registry) SetTimeout(d time.Duration) {
+	r.timeout = d
+}
+
+// SetState sets the application state
+func (r *Registry) SetState(s State) {
+	atomic.StoreInt32(&r.state, int32(s))
+}
+
+// State gets the application state
+func (r *Registry) State() State {
+	return State(atomic.LoadInt32(&r.state))
+}
+
+// Run runs all checks concurrently and reports them sorted by name
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+
+	names := make([]string, 0, len(r.checkers))
+
+	for name := range r.checkers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	checkers := make([]Checker, len(names))
+
+	for i, name := range names {
+		checkers[i] = r.checkers[name]
+	}
+
+	r.mu.RUnlock()
+
+	results := make([]Result, len(names))
+
+	var wg sync.WaitGroup
+
+	for i := range names {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			results[i] = r.check(ctx, names[i], checkers[i])
+		}(i)
+	}
+
+	wg.Wait()
+
+	report := Report{Status: StatusUp, State: r.State().String(), Checks: results}
+
+	for _, res := range results {
+		if res.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	return report
+}
+
+// check runs a checker with the registry timeout
+func (r *Registry) check(ctx context.Context, name string, c Checker) Result {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	begin := time.Now()
+	err := c.Check(ctx)
+
+	res := Result{
+		Name:      name,
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(begin)) / float64(time.Millisecond),
+	}
+
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+
+	return res
+}
+
+// LivenessHandler reports the application state without running the checks,
+// so a failing dependency does not restart the process.
+// It answers 200 while the process is serving.
+func (r *Registry) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := Report{Status: StatusUp, State: r.State().String(), Checks: []Result{}}
+		writeReport(w, report, true)
+	})
+}
+
+// ReadinessHandler reports the checks without their errors.
+// It answers 503 when a check fails or the application is not Ready.
+func (r *Registry) ReadinessHandler() http.Handler {
+	return r.readinessHandler(false)
+}
+
+// ReportHandler reports the checks with their errors, for private ports.
+// It answers like the ReadinessHandler.
+func (r *Registry) ReportHandler() http.Handler {
+	return r.readinessHandler(true)
+}
+
+// readinessHandler reports the checks, with the errors when detailed
+func (r *Registry) readinessHandler(detailed bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Run(req.Context())
+
+		if !detailed {
+			for i := range report.Checks {
+				report.Checks[i].Error = ""
+			}
+		}
+
+		writeReport(w, report, report.Status == StatusUp && r.State() == Ready)
+	})
+}
+
+// writeReport writes the report as JSON
+func writeReport(w http.ResponseWriter, report Report, ok bool) {
+	status := http.StatusOK
+
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+
+	bytes, err := json.Marshal(report)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(bytes)
+}
@@ -10,7 +10,10 @@ import (
 	"github.com/tralus/koala/token"
 )
 
-const keyJwtClaimsContext = "koala.jwt.claims.0"
+// contextKey is the type of the context keys of this package
+type contextKey string
+
+const keyJwtClaimsContext contextKey = "koala.jwt.claims.0"
 
 // Config represents the jwt settings
 type Config struct {
@@ -54,9 +57,18 @@ func (s Token) GenerateToken(claims jwt.Claims) (t token.Token, err error) {
 	return token.New(tokenStr), nil
 }
 
-// ClaimsToContext puts claims to the request context
+// WithClaims puts claims to the request context.
+// The returned request must be passed to the next handlers.
+func WithClaims(r *http.Request, c *jwt.StandardClaims) *http.Request {
+	return context.With(r, keyJwtClaimsContext, c)
+}
+
+// ClaimsToContext puts claims to the request context,
+// replacing the request in place.
+//
+// Deprecated: use WithClaims and pass the returned request to the next handlers.
 func ClaimsToContext(r *http.Request, c *jwt.StandardClaims) {
-	context.Add(r, keyJwtClaimsContext, c)
+	*r = *WithClaims(r, c)
 }
 
 // ClaimsFromContext gets claims from the request context
@@ -0,0 +1,183 @@
+package knife
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/justinas/alice"
+)
+
+// AccessLogFormat represents the format of the access log
+type AccessLogFormat int
+
+// Access log formats
+const (
+	CommonLogFormat AccessLogFormat = iota
+	CombinedLogFormat
+	JSONLogFormat
+)
+
+// clfTimeFormat is the time layout of the common log format
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogConfig represents the access log settings.
+// Skip holds regex patterns for the paths that are not logged.
+// When TrustProxy is set, the remote IP is read from X-Forwarded-For.
+type AccessLogConfig struct {
+	Format     AccessLogFormat
+	Output     io.Writer
+	Skip       []string
+	TrustProxy bool
+}
+
+// AccessLogEntry represents a request on the access log
+type AccessLogEntry struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Proto     string    `json:"proto"`
+	Route     string    `json:"route,omitempty"`
+	Status    int       `json:"status"`
+	Size      int       `json:"size"`
+	LatencyMs float64   `json:"latency_ms"`
+	RemoteIP  string    `json:"remote_ip"`
+	RequestID string    `json:"request_id,omitempty"`
+	Referer   string    `json:"referer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+}
+
+// NewAccessLogMiddleware creates a middleware that logs each request.
+// It panics when a skip pattern is not a valid regex.
+func NewAccessLogMiddleware(c AccessLogConfig) alice.Constructor {
+	out := c.Output
+
+	if out == nil {
+		out = os.Stdout
+	}
+
+	skip := make([]*regexp.Regexp, len(c.Skip))
+
+	for i, x := range c.Skip {
+		skip[i] = regexp.MustCompile(x)
+	}
+
+	var mu sync.Mutex
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, x := range skip {
+				if x.MatchString(r.URL.Path) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			begin := time.Now()
+			rw := NewResponseWriter(w)
+
+			next.ServeHTTP(rw, r)
+
+			e := AccessLogEntry{
+				Time:      begin,
+				Method:    r.Method,
+				Path:      r.URL.RequestURI(),
+				Proto:     r.Proto,
+				Route:     RouteToken(r),
+				Status:    rw.Status(),
+				Size:      rw.Size(),
+				LatencyMs: float64(time.Since(begin)) / float64(time.Millisecond),
+				RemoteIP:  remoteIP(r, c.TrustProxy),
+				RequestID: accessLogRequestID(r, rw),
+				Referer:   r.Referer(),
+				UserAgent: r.UserAgent(),
+			}
+
+			line := formatAccessLog(c.Format, e)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			out.Write(line)
+		})
+	}
+}
+
+// accessLogRequestID gets the request id from the request context,
+// the response header or the request header
+func accessLogRequestID(r *http.Request, w http.ResponseWriter) string {
+	if id := RequestID(r); id != "" {
+		return id
+	}
+
+	if id := w.Header().Get(RequestIDHeader); id != "" {
+		return id
+	}
+
+	return r.Header.Get(RequestIDHeader)
+}
+
+// formatAccessLog formats the entry as one line
+func formatAccessLog(f AccessLogFormat, e AccessLogEntry) []byte {
+	if f == JSONLogFormat {
+		bytes, _ := MarshalJSON(e)
+		return append(bytes, '\n')
+	}
+
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "%s - - [%s] \"%s %s %s\" %d %s",
+		e.RemoteIP, e.Time.Format(clfTimeFormat),
+		e.Method, e.Path, e.Proto, e.Status, clfSize(e.Size))
+
+	if f == CombinedLogFormat {
+		fmt.Fprintf(&b, " %q %q", clfValue(e.Referer), clfValue(e.UserAgent))
+	}
+
+	b.WriteByte('\n')
+
+	return b.Bytes()
+}
+
+// clfSize formats the size as "-" when it is zero
+func clfSize(n int) string {
+	if n == 0 {
+		return "-"
+	}
+
+	return fmt.Sprint(n)
+}
+
+// clfValue formats the value as "-" when it is empty
+func clfValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// remoteIP gets the client IP.
+// With trustProxy, it uses the first address on X-Forwarded-For.
+func remoteIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			return strings.TrimSpace(strings.Split(xff, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,354 @@
+package knife
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tralus/koala/errors"
+	"github.com/tralus/koala/validate"
+)
+
+// DefaultMaxMemory is the memory limit to parse multipart forms
+const DefaultMaxMemory = 32 << 20
+
+// Binder fills structs from the request.
+// The fields are filled from the body, the form or multipart fields,
+// the query string, the route params and the headers, in this order,
+// so the later sources take precedence. The struct tags are:
+//
+//	json:"name"      JSON body (encoding/json rules)
+//	xml:"name"       XML body, or other codecs of the DefaultCodecs
+//	form:"name"      url-encoded or multipart form fields and files
+//	query:"name"     query string
+//	path:"name"      route params
+//	header:"X-Name"  request headers
+type Binder struct {
+	// Rejects JSON fields that are not in the struct.
+	// The JSONCodec follows it on the DefaultBinder.
+	DisallowUnknownFields bool
+
+	// Memory limit to parse multipart forms
+	MaxMemory int64
+}
+
+// DefaultBinder is the binder used by Request.Bind
+var DefaultBinder = &Binder{MaxMemory: DefaultMaxMemory}
+
+// Bind fills dst, a pointer to a struct, from the request.
+// The conversion failures are returned as validate.FieldErrors.
+func (r Request) Bind(dst interface{}) error {
+	return DefaultBinder.Bind(&r, dst)
+}
+
+// Bind fills dst, a pointer to a struct, from the request
+func (b *Binder) Bind(r *Request, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		err := errors.New("Bind requires a not nil pointer to a struct.")
+		return errors.NewIllegalArgumentError(err)
+	}
+
+	req := r.Target()
+
+	mediatype, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	switch {
+	case mediatype == "application/json" || strings.HasSuffix(mediatype, "+json"):
+		if err := b.bindJSON(req.Body, dst); err != nil {
+			return err
+		}
+
+	case mediatype == "application/x-www-form-urlencoded":
+		if err := req.ParseForm(); err != nil {
+			return NewUnMarshalError(fmt.Sprintf("It was not possible to parse the form. Origin - %s", err))
+		}
+
+	case mediatype == "multipart/form-data":
+		max := b.MaxMemory
+
+		if max == 0 {
+			max = DefaultMaxMemory
+		}
+
+		if err := req.ParseMultipartForm(max); err != nil {
+			return NewUnMarshalError(fmt.Sprintf("It was not possible to parse the form. Origin - %s", err))
+		}
+
+	case mediatype != "" && req.ContentLength != 0:
+		codec, ok := DefaultCodecs.ForContentType(mediatype)
+
+		if !ok {
+			return NewUnsupportedMediaTypeError(fmt.Sprintf("No decoder for the Content-Type %q.", mediatype))
+		}
+
+		if err := codec.Decode(req.Body, dst); err != nil {
+			return err
+		}
+	}
+
+	var fes validate.FieldErrors
+
+	if req.PostForm != nil {
+		fes = append(fes, bindTag(v.Elem(), "form", formLookup(req))...)
+	}
+
+	if req.MultipartForm != nil {
+		bindFiles(v.Elem(), req.MultipartForm.File)
+	}
+
+	query := req.URL.Query()
+	fes = append(fes, bindTag(v.Elem(), "query", func(name string) []string {
+		return query[name]
+	})...)
+
+	params := r.Params()
+	fes = append(fes, bindTag(v.Elem(), "path", func(name string) []string {
+		if s := params.AsString(name); s != "" {
+			return []string{s}
+		}
+		return nil
+	})...)
+
+	fes = append(fes, bindTag(v.Elem(), "header", func(name string) []string {
+		return req.Header[textproto.CanonicalMIMEHeaderKey(name)]
+	})...)
+
+	if len(fes) > 0 {
+		return validate.NewFieldErrors(fes...)
+	}
+
+	return nil
+}
+
+// bindJSON decodes the JSON body into dst.
+// An empty body is ignored.
+func (b *Binder) bindJSON(body io.Reader, dst interface{}) error {
+	data, err := ioutil.ReadAll(body)
+
+	if err != nil {
+		return NewUnMarshalError(fmt.Sprintf("It was not possible to read body json. Origin - %s", err))
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	if b.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	err = dec.Decode(dst)
+
+	if err == nil {
+		return nil
+	}
+
+	if e, ok := err.(*json.UnmarshalTypeError); ok {
+		return validate.NewFieldErrors(validate.FieldError{
+			Field:   e.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("must be %s", e.Type),
+		})
+	}
+
+	if strings.HasPrefix(err.Error(), "json: unknown field ") {
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+
+		return validate.NewFieldErrors(validate.FieldError{
+			Field:   field,
+			Rule:    "unknown",
+			Message: "is not allowed",
+		})
+	}
+
+	return NewUnMarshalError(fmt.Sprintf("It was not possible to decode json. Origin - %s", err))
+}
+
+// formLookup gets the form values, including the multipart values
+func formLookup(req *http.Request) func(string) []string {
+	return func(name string) []string {
+		if vs, ok := req.PostForm[name]; ok {
+			return vs
+		}
+
+		if req.MultipartForm != nil {
+			return req.MultipartForm.Value[name]
+		}
+
+		return nil
+	}
+}
+
+// bindTag fills the fields of v with the tag from the lookup values.
+// The fields without the tag are skipped, but the structs are visited.
+func bindTag(v reflect.Value, tag string, lookup func(string) []string) validate.FieldErrors {
+	var fes validate.FieldErrors
+
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+
+		if sf.PkgPath != "" {
+			// only the fields promoted by the unexported embedded structs are set
+			if sf.Anonymous && fv.Kind() == reflect.Struct {
+				fes = append(fes, bindTag(fv, tag, lookup)...)
+			}
+			continue
+		}
+
+		name := strings.Split(sf.Tag.Get(tag), ",")[0]
+
+		if name == "" || name == "-" {
+			if fv.Kind() == reflect.Struct && !isScalarStruct(fv.Type()) {
+				fes = append(fes, bindTag(fv, tag, lookup)...)
+			}
+			continue
+		}
+
+		values := lookup(name)
+
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := setValues(fv, values); err != nil {
+			fes = append(fes, validate.FieldError{Field: name, Rule: "type", Message: err.Error()})
+		}
+	}
+
+	return fes
+}
+
+// bindFiles fills the *multipart.FileHeader fields with the form tag
+func bindFiles(v reflect.Value, files map[string][]*multipart.FileHeader) {
+	fileType := reflect.TypeOf((*multipart.FileHeader)(nil))
+
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		name := strings.Split(sf.Tag.Get("form"), ",")[0]
+
+		if sf.PkgPath != "" || name == "" || len(files[name]) == 0 {
+			continue
+		}
+
+		switch {
+		case sf.Type == fileType:
+			fv.Set(reflect.ValueOf(files[name][0]))
+		case sf.Type.Kind() == reflect.Slice && sf.Type.Elem() == fileType:
+			fv.Set(reflect.ValueOf(files[name]))
+		}
+	}
+}
+
+// textUnmarshalerType is the type of encoding.TextUnmarshaler
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// isScalarStruct verifies if the struct is set from one value, like time.Time
+func isScalarStruct(t reflect.Type) bool {
+	return t == reflect.TypeOf(time.Time{}) || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// setValues sets the values on v.
+// Slices get all values, other kinds get the first value.
+func setValues(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+
+		for i, value := range values {
+			if err := setValue(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+
+		v.Set(s)
+
+		return nil
+	}
+
+	return setValue(v, values[0])
+}
+
+// setValue converts the string to the kind of v and sets it
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		return setValue(v.Elem(), s)
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return errors.Errorf("must be a valid %s", v.Type())
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.Errorf("must be a boolean")
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return errors.Errorf("must be a duration")
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.Errorf("must be an integer")
+		}
+		v.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.Errorf("must be a positive integer")
+		}
+		v.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return errors.Errorf("must be a number")
+		}
+		v.SetFloat(f)
+
+	default:
+		return errors.Errorf("has the unsupported type %s", v.Type())
+	}
+
+	return nil
+}
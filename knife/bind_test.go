@@ -0,0 +1,102 @@
+package knife
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tralus/koala/validate"
+)
+
+type bindCode string
+
+type bindPage struct {
+	Page int `query:"page"`
+}
+
+type bindInput struct {
+	bindCode `query:"code"`
+	bindPage
+
+	Name  string `json:"name" query:"name"`
+	Limit int    `query:"limit"`
+	Token string `header:"X-Token"`
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		body   string
+		want   bindInput
+		fields []string
+	}{
+		{"query", "/?name=ana&limit=5", "", bindInput{Name: "ana", Limit: 5, Token: "t"}, nil},
+		{"json body", "/", `{"name":"ana"}`, bindInput{Name: "ana", Token: "t"}, nil},
+		{"query over body", "/?name=bia", `{"name":"ana"}`, bindInput{Name: "bia", Token: "t"}, nil},
+		{"embedded struct", "/?page=3", "", bindInput{bindPage: bindPage{3}, Token: "t"}, nil},
+		{"unexported embedded", "/?code=a", "", bindInput{Token: "t"}, nil},
+		{"invalid type", "/?limit=x", "", bindInput{Token: "t"}, []string{"limit"}},
+		{"invalid json type", "/", `{"name":1}`, bindInput{Token: "t"}, []string{"name"}},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.url, strings.NewReader(tt.body))
+		req.Header.Set("X-Token", "t")
+
+		if tt.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		var got bindInput
+
+		err := NewRequest(req).Bind(&got)
+
+		if tt.fields != nil {
+			fes, ok := validate.GetFieldErrors(err)
+
+			if !ok || len(fes) != len(tt.fields) || fes[0].Field != tt.fields[0] {
+				t.Errorf("%s: expected field errors %v, got %v", tt.name, tt.fields, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+
+		if got.Name != tt.want.Name || got.Limit != tt.want.Limit || got.Page != tt.want.Page ||
+			got.Token != tt.want.Token || got.bindCode != "" {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestBindDisallowUnknownFields(t *testing.T) {
+	DefaultBinder.DisallowUnknownFields = true
+	defer func() { DefaultBinder.DisallowUnknownFields = false }()
+
+	for _, decode := range []string{"Bind", "Decode"} {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"ana","age":1}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		var v struct {
+			Name string `json:"name"`
+		}
+
+		var err error
+
+		if decode == "Bind" {
+			err = NewRequest(req).Bind(&v)
+		} else {
+			err = NewRequest(req).Decode(&v)
+		}
+
+		fes, ok := validate.GetFieldErrors(err)
+
+		if !ok || len(fes) != 1 || fes[0].Field != "age" {
+			t.Errorf("%s: expected the age field error, got %v", decode, err)
+		}
+	}
+}
@@ -0,0 +1,635 @@
+package knife
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/justinas/alice"
+	"github.com/tralus/koala/errors"
+	"github.com/tralus/koala/validate"
+)
+
+// Codec encodes and decodes a media type
+type Codec interface {
+	// The media type, like "application/json"
+	MediaType() string
+
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+// Codecs represents a codec registry.
+// The first codec is the default one.
+type Codecs struct {
+	mu     sync.RWMutex
+	codecs []Codec
+}
+
+// NewCodecs creates a *Codecs instance
+func NewCodecs(codecs ...Codec) *Codecs {
+	return &Codecs{codecs: codecs}
+}
+
+// DefaultCodecs is the registry used by Response.Negotiate and Request.Decode
+var DefaultCodecs = NewCodecs(JSONCodec{}, XMLCodec{}, CSVCodec{}, FormCodec{})
+
+// RegisterCodec registers a codec on the DefaultCodecs
+func RegisterCodec(c Codec) {
+	DefaultCodecs.Register(c)
+}
+
+// Register registers a codec.
+// A codec for the same media type is replaced.
+func (c *Codecs) Register(codec Codec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, existing := range c.codecs {
+		if existing.MediaType() == codec.MediaType() {
+			c.codecs[i] = codec
+			return
+		}
+	}
+
+	c.codecs = append(c.codecs, codec)
+}
+
+// ForContentType gets the codec for a Content-Type header.
+// A structured suffix matches too, like application/problem+json.
+func (c *Codecs) ForContentType(contentType string) (Codec, bool) {
+	mediatype, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, codec := range c.codecs {
+		if codec.MediaType() == mediatype {
+			return codec, true
+		}
+	}
+
+	if i := strings.LastIndex(mediatype, "+"); i >= 0 {
+		for _, codec := range c.codecs {
+			if strings.HasSuffix(codec.MediaType(), "/"+mediatype[i+1:]) {
+				return codec, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// ForAccept gets the codec for an Accept header.
+// The media ranges are tried by their quality, and an empty header
+// gets the default codec.
+func (c *Codecs) ForAccept(accept string) (Codec, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.codecs) == 0 {
+		return nil, false
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return c.codecs[0], true
+	}
+
+	for _, mediaRange := range parseAccept(accept) {
+		for _, codec := range c.codecs {
+			if matchMediaRange(mediaRange, codec.MediaType()) {
+				return codec, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// parseAccept gets the media ranges of an Accept header sorted by quality.
+// The ranges with quality 0 are dropped.
+func parseAccept(accept string) []string {
+	type mediaRange struct {
+		value string
+		q     float64
+	}
+
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(accept, ",") {
+		mediatype, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil {
+				continue
+			}
+		}
+
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediatype, q})
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	values := make([]string, len(ranges))
+
+	for i, r := range ranges {
+		values[i] = r.value
+	}
+
+	return values
+}
+
+// matchMediaRange verifies if the media type matches the range, like "text/*"
+func matchMediaRange(mediaRange, mediatype string) bool {
+	if mediaRange == "*/*" || mediaRange == mediatype {
+		return true
+	}
+
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(mediatype, strings.TrimSuffix(mediaRange, "*"))
+	}
+
+	return false
+}
+
+// Negotiate creates a response encoding v with the codec for the
+// Accept header of the request. It answers NotAcceptable when no
+// codec matches.
+func (r Response) Negotiate(v interface{}) (Response, error) {
+	accept := ""
+
+	if r.request != nil {
+		accept = r.request.Header.Get("Accept")
+	}
+
+	r.Header().Add("Vary", "Accept")
+
+	codec, ok := DefaultCodecs.ForAccept(accept)
+
+	if !ok {
+		r.SetStatus(http.StatusNotAcceptable)
+		return r, NewNotAcceptableError(fmt.Sprintf("No encoder for the Accept %q.", accept))
+	}
+
+	var buf bytes.Buffer
+
+	if err := codec.Encode(&buf, v); err != nil {
+		return r, err
+	}
+
+	r.SetContentType(codec.MediaType() + "; charset=utf-8")
+
+	return r.Ok(buf.Bytes())
+}
+
+// Decode decodes the body into v with the codec for the Content-Type.
+// The body without Content-Type is decoded by the default codec.
+// It returns an UnsupportedMediaTypeError when no codec matches.
+func (r Request) Decode(v interface{}) error {
+	contentType := r.target.Header.Get("Content-Type")
+
+	codec, ok := DefaultCodecs.ForContentType(contentType)
+
+	if contentType == "" {
+		codec, ok = DefaultCodecs.ForAccept("")
+	}
+
+	if !ok {
+		return NewUnsupportedMediaTypeError(fmt.Sprintf("No decoder for the Content-Type %q.", contentType))
+	}
+
+	return codec.Decode(r.target.Body, v)
+}
+
+// NewContentTypeMiddleware creates a middleware answering UnsupportedMediaType
+// for the request bodies without a codec on c
+func NewContentTypeMiddleware(c *Codecs) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType := r.Header.Get("Content-Type")
+
+			if r.ContentLength != 0 && contentType != "" {
+				if _, ok := c.ForContentType(contentType); !ok {
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+					w.Write([]byte(fmt.Sprintf("Unsupported Content-Type %q.", contentType)))
+
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NotAcceptableError represents a response without an encoder for the Accept
+type NotAcceptableError struct {
+	Msg string
+}
+
+// NewNotAcceptableError creates a NotAcceptableError instance
+func NewNotAcceptableError(msg string) NotAcceptableError {
+	return NotAcceptableError{msg}
+}
+
+// IsNotAcceptableError verifies if error is a NotAcceptableError
+func IsNotAcceptableError(err error) bool {
+	_, ok := errors.Cause(err).(NotAcceptableError)
+	return ok
+}
+
+// Error gets the error message
+func (e NotAcceptableError) Error() string {
+	return e.Msg
+}
+
+// UnsupportedMediaTypeError represents a body without a decoder for the Content-Type
+type UnsupportedMediaTypeError struct {
+	Msg string
+}
+
+// NewUnsupportedMediaTypeError creates an UnsupportedMediaTypeError instance
+func NewUnsupportedMediaTypeError(msg string) UnsupportedMediaTypeError {
+	return UnsupportedMediaTypeError{msg}
+}
+
+// IsUnsupportedMediaTypeError verifies if error is an UnsupportedMediaTypeError
+func IsUnsupportedMediaTypeError(err error) bool {
+	_, ok := errors.Cause(err).(UnsupportedMediaTypeError)
+	return ok
+}
+
+// Error gets the error message
+func (e UnsupportedMediaTypeError) Error() string {
+	return e.Msg
+}
+
+// JSONCodec encodes and decodes application/json
+type JSONCodec struct{}
+
+// MediaType implements Codec
+func (JSONCodec) MediaType() string {
+	return "application/json"
+}
+
+// Encode implements Codec
+func (JSONCodec) Encode(w io.Writer, v interface{}) error {
+	bytes, err := MarshalJSON(v)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+
+	return err
+}
+
+// Decode implements Codec.
+// The type failures are returned as validate.FieldErrors.
+// The unknown fields are rejected like on the DefaultBinder.
+func (JSONCodec) Decode(r io.Reader, v interface{}) error {
+	return DefaultBinder.bindJSON(r, v)
+}
+
+// XMLCodec encodes and decodes application/xml
+type XMLCodec struct{}
+
+// MediaType implements Codec
+func (XMLCodec) MediaType() string {
+	return "application/xml"
+}
+
+// Encode implements Codec
+func (XMLCodec) Encode(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// Decode implements Codec
+func (XMLCodec) Decode(r io.Reader, v interface{}) error {
+	if err := xml.NewDecoder(r).Decode(v); err != nil && err != io.EOF {
+		return NewUnMarshalError(fmt.Sprintf("It was not possible to decode xml. Origin - %s", err))
+	}
+
+	return nil
+}
+
+// CSVCodec encodes and decodes text/csv for slices of structs.
+// The columns are the exported fields, named by the csv tag or the field name.
+// The first row is the header.
+type CSVCodec struct{}
+
+// MediaType implements Codec
+func (CSVCodec) MediaType() string {
+	return "text/csv"
+}
+
+// Encode implements Codec
+func (CSVCodec) Encode(w io.Writer, v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+
+	if rv.Kind() != reflect.Slice || csvElem(rv.Type()).Kind() != reflect.Struct {
+		return errors.NewIllegalArgumentError(errors.Errorf("CSV requires a slice of structs, got %T.", v))
+	}
+
+	fields := csvFields(csvElem(rv.Type()))
+	cw := csv.NewWriter(w)
+	row := make([]string, len(fields))
+
+	for i, f := range fields {
+		row[i] = f.name
+	}
+
+	cw.Write(row)
+
+	for i := 0; i < rv.Len(); i++ {
+		elem := reflect.Indirect(rv.Index(i))
+
+		for j, f := range fields {
+			if elem.IsValid() {
+				row[j] = formatValue(elem.Field(f.index))
+			} else {
+				row[j] = ""
+			}
+		}
+
+		cw.Write(row)
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// Decode implements Codec.
+// It requires a pointer to a slice of structs.
+// The conversion failures are returned as validate.FieldErrors.
+func (CSVCodec) Decode(r io.Reader, v interface{}) error {
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice ||
+		csvElem(rv.Elem().Type()).Kind() != reflect.Struct {
+		return errors.NewIllegalArgumentError(errors.Errorf("CSV requires a pointer to a slice of structs, got %T.", v))
+	}
+
+	records, err := csv.NewReader(r).ReadAll()
+
+	if err != nil {
+		return NewUnMarshalError(fmt.Sprintf("It was not possible to decode csv. Origin - %s", err))
+	}
+
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	fields := make(map[string]int)
+
+	for _, f := range csvFields(csvElem(slice.Type())) {
+		fields[f.name] = f.index
+	}
+
+	var fes validate.FieldErrors
+
+	for i := 1; i < len(records); i++ {
+		elem := reflect.New(csvElem(slice.Type())).Elem()
+
+		for j, s := range records[i] {
+			index, ok := fields[records[0][j]]
+
+			if !ok || s == "" {
+				continue
+			}
+
+			if err := setValue(elem.Field(index), s); err != nil {
+				field := fmt.Sprintf("[%d].%s", i-1, records[0][j])
+				fes = append(fes, validate.FieldError{Field: field, Rule: "type", Message: err.Error()})
+			}
+		}
+
+		if elemType.Kind() == reflect.Ptr {
+			elem = elem.Addr()
+		}
+
+		slice = reflect.Append(slice, elem)
+	}
+
+	if len(fes) > 0 {
+		return validate.NewFieldErrors(fes...)
+	}
+
+	rv.Elem().Set(slice)
+
+	return nil
+}
+
+// csvField represents a CSV column
+type csvField struct {
+	name  string
+	index int
+}
+
+// csvElem gets the struct type of the slice elements
+func csvElem(t reflect.Type) reflect.Type {
+	t = t.Elem()
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// csvFields gets the columns of the struct
+func csvFields(t reflect.Type) []csvField {
+	var fields []csvField
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(sf.Tag.Get("csv"), ",")[0]
+
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, csvField{name, i})
+	}
+
+	return fields
+}
+
+// formatValue formats a field value for a CSV cell or a form value
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch t := v.Interface().(type) {
+	case time.Time:
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	case encoding.TextMarshaler:
+		b, err := t.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+// FormCodec encodes and decodes application/x-www-form-urlencoded.
+// The structs use the form tag, like the Binder.
+type FormCodec struct{}
+
+// MediaType implements Codec
+func (FormCodec) MediaType() string {
+	return "application/x-www-form-urlencoded"
+}
+
+// Encode implements Codec.
+// It encodes url.Values, map[string]string and structs.
+func (FormCodec) Encode(w io.Writer, v interface{}) error {
+	values := make(url.Values)
+
+	switch t := v.(type) {
+	case url.Values:
+		values = t
+	case map[string]string:
+		for k, s := range t {
+			values.Set(k, s)
+		}
+	default:
+		rv := reflect.Indirect(reflect.ValueOf(v))
+
+		if rv.Kind() != reflect.Struct {
+			return errors.NewIllegalArgumentError(errors.Errorf("Form requires a struct or a map, got %T.", v))
+		}
+
+		formValues(rv, values)
+	}
+
+	_, err := io.WriteString(w, values.Encode())
+
+	return err
+}
+
+// formValues adds the fields with the form tag to the values
+func formValues(v reflect.Value, values url.Values) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+
+		if sf.PkgPath != "" {
+			if sf.Anonymous && fv.Kind() == reflect.Struct {
+				formValues(fv, values)
+			}
+			continue
+		}
+
+		name := strings.Split(sf.Tag.Get("form"), ",")[0]
+
+		if name == "" || name == "-" {
+			if fv.Kind() == reflect.Struct && !isScalarStruct(fv.Type()) {
+				formValues(fv, values)
+			}
+			continue
+		}
+
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			for j := 0; j < fv.Len(); j++ {
+				values.Add(name, formatValue(fv.Index(j)))
+			}
+			continue
+		}
+
+		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			continue
+		}
+
+		values.Set(name, formatValue(fv))
+	}
+}
+
+// Decode implements Codec.
+// It decodes into url.Values or a pointer to a struct.
+// The conversion failures are returned as validate.FieldErrors.
+func (FormCodec) Decode(r io.Reader, v interface{}) error {
+	data, err := ioutil.ReadAll(r)
+
+	if err != nil {
+		return NewUnMarshalError(fmt.Sprintf("It was not possible to read the form. Origin - %s", err))
+	}
+
+	values, err := url.ParseQuery(string(data))
+
+	if err != nil {
+		return NewUnMarshalError(fmt.Sprintf("It was not possible to parse the form. Origin - %s", err))
+	}
+
+	if t, ok := v.(*url.Values); ok {
+		*t = values
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.NewIllegalArgumentError(errors.Errorf("Form requires a pointer to a struct, got %T.", v))
+	}
+
+	fes := bindTag(rv.Elem(), "form", func(name string) []string {
+		return values[name]
+	})
+
+	if len(fes) > 0 {
+		return validate.NewFieldErrors(fes...)
+	}
+
+	return nil
+}
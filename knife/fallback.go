@@ -0,0 +1,198 @@
+package knife
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/justinas/alice"
+	"github.com/tralus/koala/errors"
+)
+
+// fallbackMethods are the methods verified for the Allow header
+var fallbackMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodTrace,
+	http.MethodOptions,
+}
+
+// SetNotFound defines the handler for the paths without routes.
+// It runs through the global middlewares and the error handler.
+func (r *Router) SetNotFound(h HandlerFunc) {
+	r.notFound = h
+}
+
+// SetMethodNotAllowed defines the handler for the paths without routes
+// for the request method. The Allow header is already set when it runs.
+// It runs through the global middlewares and the error handler.
+func (r *Router) SetMethodNotAllowed(h HandlerFunc) {
+	r.notAllowed = h
+}
+
+// FallbackMatcher is implemented by the fallbacks serving only some paths,
+// like the static handler. The other paths get the NotFound handler.
+// The paths under the route groups never get the fallback.
+type FallbackMatcher interface {
+	Matches(req *http.Request) bool
+}
+
+// SetFallback defines a handler for the paths without routes, like a
+// static or single-page app handler. It runs through the global
+// middlewares instead of the NotFound handler, unless it is a
+// FallbackMatcher not matching the request.
+func (r *Router) SetFallback(h http.Handler) {
+	r.fallback = h
+}
+
+// NotFoundHandler answers NotFound with a NotFoundError
+func NotFoundHandler(resp Response, req *Request) (Response, error) {
+	resp.SetStatus(http.StatusNotFound)
+	resp.SetContentType("text/plain; charset=utf-8")
+	resp.SetBytes([]byte(http.StatusText(http.StatusNotFound)))
+
+	err := errors.Errorf("The path %s was not found.", req.URL().Path)
+
+	return resp, errors.NewNotFoundError(err)
+}
+
+// MethodNotAllowedHandler answers MethodNotAllowed
+func MethodNotAllowedHandler(resp Response, req *Request) (Response, error) {
+	resp.SetStatus(http.StatusMethodNotAllowed)
+	resp.SetContentType("text/plain; charset=utf-8")
+	resp.SetBytes([]byte(http.StatusText(http.StatusMethodNotAllowed)))
+
+	return resp, errors.Errorf("The method %s is not allowed for the path %s.",
+		req.Target().Method, req.URL().Path)
+}
+
+// optionsHandler answers the automatic OPTIONS requests
+func optionsHandler(resp Response, req *Request) (Response, error) {
+	return resp.NoContent()
+}
+
+// fallbackHandler answers the requests not matched by the routes.
+// The OPTIONS requests get the Allow header of the path, the other
+// methods of known paths get MethodNotAllowed, and the unknown
+// paths get the fallback or NotFound. They run through the global middlewares.
+func (r *Router) fallbackHandler() http.Handler {
+	var globals []Middleware
+
+	for _, middleware := range r.middlewares {
+		if middleware.Silent == false {
+			globals = append(globals, middleware)
+		}
+	}
+
+	notFound, notAllowed := r.notFound, r.notAllowed
+
+	if notFound == nil {
+		notFound = NotFoundHandler
+	}
+
+	if notAllowed == nil {
+		notAllowed = MethodNotAllowedHandler
+	}
+
+	notFoundHandler := r.chain(globals, notFound)
+	notAllowedHandler := r.chain(globals, notAllowed)
+	optionsHandler := r.chain(globals, optionsHandler)
+
+	var fallbackHandler http.Handler
+
+	if r.fallback != nil {
+		chain := alice.New()
+
+		for _, middleware := range globals {
+			chain = chain.Append(middleware.Constructor)
+		}
+
+		fallbackHandler = chain.Then(r.fallback)
+	}
+
+	var prefixes []string
+
+	for _, g := range r.groups {
+		prefixes = append(prefixes, g.Path())
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		allow := r.allowed(req.URL.Path)
+
+		switch {
+		case len(allow) == 0 && r.fallbackMatches(req, prefixes):
+			fallbackHandler.ServeHTTP(w, req)
+		case len(allow) == 0:
+			notFoundHandler.ServeHTTP(w, req)
+		case req.Method == http.MethodOptions:
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			optionsHandler.ServeHTTP(w, req)
+		default:
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			notAllowedHandler.ServeHTTP(w, req)
+		}
+	})
+}
+
+// fallbackMatches verifies if the fallback serves the request.
+// The paths under the group prefixes are not served.
+func (r *Router) fallbackMatches(req *http.Request, prefixes []string) bool {
+	if r.fallback == nil {
+		return false
+	}
+
+	for _, p := range prefixes {
+		if req.URL.Path == p || strings.HasPrefix(req.URL.Path, p+"/") {
+			return false
+		}
+	}
+
+	if m, ok := r.fallback.(FallbackMatcher); ok {
+		return m.Matches(req)
+	}
+
+	return true
+}
+
+// allowed gets the methods with routes for the path, with OPTIONS.
+// The path "*" gets the methods of all routes.
+func (r *Router) allowed(path string) []string {
+	var allow []string
+
+	for _, method := range fallbackMethods {
+		if path == "*" {
+			if r.hasMethod(method) {
+				allow = append(allow, method)
+			}
+			continue
+		}
+
+		if h, _, _ := r.Router.Lookup(method, path); h != nil {
+			allow = append(allow, method)
+		}
+	}
+
+	if len(allow) > 0 && allow[len(allow)-1] != http.MethodOptions {
+		allow = append(allow, http.MethodOptions)
+	}
+
+	return allow
+}
+
+// hasMethod verifies if a route uses the method.
+// The mounted handlers use all methods.
+func (r *Router) hasMethod(method string) bool {
+	for _, routes := range r.routes {
+		for _, route := range routes {
+			if route.MethodName == method || route.mounted != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,122 @@
+package knife
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/justinas/alice"
+)
+
+// groupRegex validates the group names
+const groupRegex = "^[\\w-]+$"
+
+// Group represents a group of routes.
+// A child group composes the path and token prefixes of its parents,
+// and its middlewares run after the middlewares of its parents.
+type Group struct {
+	router      *Router
+	parent      *Group
+	name        string
+	middlewares []Middleware
+}
+
+// Group gets the group for the name, creating it on the first call
+func (r *Router) Group(name string) *Group {
+	return r.group(nil, name)
+}
+
+// Group gets the child group for the name, creating it on the first call
+func (g *Group) Group(name string) *Group {
+	return g.router.group(g, name)
+}
+
+// group gets or creates a group of the router
+func (r *Router) group(parent *Group, name string) *Group {
+	matched, err := regexp.MatchString(groupRegex, name)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if !matched {
+		m := "knife: group %s does not match to the %s regex."
+		panic(fmt.Sprintf(m, name, groupRegex))
+	}
+
+	g := &Group{router: r, parent: parent, name: name}
+
+	if existing, ok := r.groups[g.Token()]; ok {
+		return existing
+	}
+
+	r.groups[g.Token()] = g
+
+	return g
+}
+
+// Token gets the token prefix of the group, like "api.v1"
+func (g *Group) Token() string {
+	if g.parent == nil {
+		return g.name
+	}
+
+	return g.parent.Token() + "." + g.name
+}
+
+// Path gets the path prefix of the group, like "/api/v1"
+func (g *Group) Path() string {
+	if g.parent == nil {
+		return "/" + g.name
+	}
+
+	return g.parent.Path() + "/" + g.name
+}
+
+// Use adds a middleware to the group and its children
+func (g *Group) Use(token string, constructor alice.Constructor) *Group {
+	g.middlewares = append(g.middlewares, Middleware{token, constructor, false})
+	return g
+}
+
+// Middlewares gets the middlewares of the group, from the parents to the child
+func (g *Group) Middlewares() []Middleware {
+	if g.parent == nil {
+		return append([]Middleware{}, g.middlewares...)
+	}
+
+	return append(g.parent.Middlewares(), g.middlewares...)
+}
+
+// AddRoutes adds one or more routes to the group.
+// The route token and path are prefixed by the group token and path.
+func (g *Group) AddRoutes(newRoutes ...*Route) {
+	token := g.Token()
+	oldRoutes := g.router.routes[token]
+
+	for i, nr := range newRoutes {
+		nr.Token = token + "." + nr.Token
+
+		// the tokens of the nested groups are composed, so all routes are verified
+		_, exists := g.router.route(nr.Token)
+
+		for _, pr := range newRoutes[:i] {
+			exists = exists || pr.Token == nr.Token
+		}
+
+		if exists {
+			m := "knife: many registrations for route '%s' on group '%s'."
+			panic(fmt.Sprintf(m, nr.Token, token))
+		}
+
+		sep := ""
+		if !strings.HasPrefix(nr.Path, "/") {
+			sep = "/"
+		}
+
+		nr.Path = g.Path() + sep + nr.Path
+		nr.group = g
+	}
+
+	g.router.routes[token] = append(oldRoutes, newRoutes...)
+}
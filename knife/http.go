@@ -1,6 +1,7 @@
 package knife
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -9,14 +10,16 @@ import (
 	"mime"
 	"net/http"
 	"net/url"
-	"regexp"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/gorilla/context"
 	"github.com/julienschmidt/httprouter"
 	"github.com/justinas/alice"
+	"github.com/tralus/koala/errors"
+	"github.com/tralus/koala/logger"
 )
 
 // SupressError sets if http error should be sent
@@ -26,9 +29,12 @@ func init() {
 	flag.BoolVar(&SupressError, "koala_knife_supress_error", false, "suppress error")
 }
 
-// RouteParams represents the params for a route
+// RouteParams represents the params for a route.
+// The embedded Values provides the strict typed accessors.
 type RouteParams struct {
 	Params httprouter.Params
+
+	Values
 }
 
 // AsString gets the param as an String
@@ -47,13 +53,42 @@ func (p RouteParams) AsInt(name string) int {
 	return i
 }
 
+// contextKey is the type of the context keys of this package
+type contextKey string
+
+// Context keys
+const (
+	paramsKey contextKey = "knife.params"
+	routeKey  contextKey = "knife.route"
+)
+
+// withValue creates a shallow copy of r with the value added to its context
+func withValue(r *http.Request, key contextKey, val interface{}) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), key, val))
+}
+
 // HTTPRouterWrapHandler wraps the http.Handler with a httprouter.Handle
 // The httprouter.Handle supports better parse of URL params
 func HTTPRouterWrapHandler(h http.Handler) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		context.Set(r, "params", ps)
-		h.ServeHTTP(w, r)
+		h.ServeHTTP(w, withValue(r, paramsKey, ps))
+	}
+}
+
+// routeTokenHandler puts the route token to the request context
+func routeTokenHandler(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, withValue(r, routeKey, token))
+	})
+}
+
+// RouteToken gets the token of the route matched by the request
+func RouteToken(r *http.Request) string {
+	if token, ok := r.Context().Value(routeKey).(string); ok {
+		return token
 	}
+
+	return ""
 }
 
 // HTTPMethod represents a http method
@@ -64,9 +99,19 @@ type Router struct {
 	*httprouter.Router
 
 	routes         Routes
+	groups         map[string]*Group
 	middlewares    []Middleware
 	middlewaresMap MiddlewaresMap
 	errorHandler   ErrorHandler
+	metrics        *Metrics
+	logger         *logger.Logger
+	openAPIPath    string
+	openAPIInfo    OpenAPIInfo
+	notFound       HandlerFunc
+	notAllowed     HandlerFunc
+	fallback       http.Handler
+	renderer       *Renderer
+	started        bool
 }
 
 // Routes represents the map de routes
@@ -75,9 +120,97 @@ type Routes map[string][]*Route
 // NewRouter creates an instance of the *Router
 func NewRouter() *Router {
 	return &Router{
-		Router: httprouter.New(),
-		routes: make(Routes),
+		Router:  httprouter.New(),
+		routes:  make(Routes),
+		groups:  make(map[string]*Group),
+		metrics: NewMetrics(),
+	}
+}
+
+// Metrics gets the route metrics collected by the router
+func (r *Router) Metrics() *Metrics {
+	return r.metrics
+}
+
+// RouteInfo represents the public data of a route
+type RouteInfo struct {
+	Token       string   `json:"token"`
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Middlewares []string `json:"middlewares"`
+}
+
+// RouteTable gets the registered routes sorted by path and method.
+// The middlewares are the effective chain of each route, in order.
+func (r *Router) RouteTable() []RouteInfo {
+	var table []RouteInfo
+
+	for _, routes := range r.routes {
+		for _, route := range routes {
+			middlewares := []string{}
+
+			for _, m := range r.routeMiddlewares(route) {
+				middlewares = append(middlewares, m.Token)
+			}
+
+			table = append(table, RouteInfo{route.Token, route.MethodName, route.Path, middlewares})
+		}
+	}
+
+	sort.Slice(table, func(i, j int) bool {
+		if table[i].Path == table[j].Path {
+			return table[i].Method < table[j].Method
+		}
+		return table[i].Path < table[j].Path
+	})
+
+	return table
+}
+
+// RouteInfo gets the public data of the route registered for the token
+func (r *Router) RouteInfo(token string) (RouteInfo, bool) {
+	for _, info := range r.RouteTable() {
+		if info.Token == token {
+			return info, true
+		}
+	}
+
+	return RouteInfo{}, false
+}
+
+// RouteTableHandler serves the route table as JSON
+func (r *Router) RouteTableHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		bytes, err := MarshalJSON(r.RouteTable())
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(bytes)
+	})
+}
+
+// SetLogger defines the logger for the router
+func (r *Router) SetLogger(l *logger.Logger) {
+	r.logger = l
+}
+
+// Logger gets the logger of the router.
+// It is nil when no logger was defined.
+func (r *Router) Logger() *logger.Logger {
+	return r.logger
+}
+
+// log gets the router logger or the default logger
+func (r *Router) log() *logger.Logger {
+	if r.logger == nil {
+		return logger.Default()
 	}
+
+	return r.logger
 }
 
 // SetErrorHandler defines the error handler for the router
@@ -95,75 +228,52 @@ func (r *Router) SetMiddlewaresMap(m MiddlewaresMap) {
 	r.middlewaresMap = m
 }
 
-// AddRoutes adds one or more routes for the routes
+// AddRoutes adds one or more routes for the routes.
+// It is a shortcut for r.Group(g).AddRoutes(newRoutes...).
 func (r *Router) AddRoutes(g string, newRoutes ...*Route) {
-	oldRoutes := r.routes[g]
-
-	x := "^[\\w-]+$"
-	matched, err := regexp.MatchString(x, g)
-
-	if err != nil {
-		panic(err)
-	}
-
-	if !matched {
-		m := "knife: group %s does not match to the %s regex."
-		panic(fmt.Sprintf(m, g, x))
-	}
-
-	for _, nr := range newRoutes {
-		for _, or := range oldRoutes {
-			if or.Token == nr.Token {
-				m := "knife: many registrations for route '%s' on group '%s'."
-				panic(fmt.Sprintf(m, nr.Token, g))
-			}
-		}
-
-		sep := ""
-		if !strings.HasPrefix(nr.Path, "/") {
-			sep = "/"
-		}
-
-		nr.Token = g + "." + nr.Token
-		nr.Path = "/" + g + sep + nr.Path
-	}
+	r.Group(g).AddRoutes(newRoutes...)
+}
 
-	r.routes[g] = append(oldRoutes, newRoutes...)
+// newRoute creates a route with the HTTP method name
+func (r *Router) newRoute(token, name string, method HTTPMethod, path string, h Handler) *Route {
+	route := NewRoute(token, method, path, h)
+	route.MethodName = name
+	return route
 }
 
 // GET creates a new route for HTTP GET
 func (r *Router) GET(token string, path string, h Handler) *Route {
-	return NewRoute(token, r.Router.GET, path, h)
+	return r.newRoute(token, http.MethodGet, r.Router.GET, path, h)
 }
 
 // POST creates a new route for HTTP POST
 func (r *Router) POST(token string, path string, h Handler) *Route {
-	return NewRoute(token, r.Router.POST, path, h)
+	return r.newRoute(token, http.MethodPost, r.Router.POST, path, h)
 }
 
 // DELETE creates a new route for HTTP DELETE
 func (r *Router) DELETE(token string, path string, h Handler) *Route {
-	return NewRoute(token, r.Router.DELETE, path, h)
+	return r.newRoute(token, http.MethodDelete, r.Router.DELETE, path, h)
 }
 
 // PUT creates a new route for HTTP PUT
 func (r *Router) PUT(token string, path string, h Handler) *Route {
-	return NewRoute(token, r.Router.PUT, path, h)
+	return r.newRoute(token, http.MethodPut, r.Router.PUT, path, h)
 }
 
 // PATCH creates a new route for HTTP PATCH
 func (r *Router) PATCH(token string, path string, h Handler) *Route {
-	return NewRoute(token, r.Router.PATCH, path, h)
+	return r.newRoute(token, http.MethodPatch, r.Router.PATCH, path, h)
 }
 
 // OPTIONS creates a new route for HTTP OPTIONS
 func (r *Router) OPTIONS(token string, path string, h Handler) *Route {
-	return NewRoute(token, r.Router.OPTIONS, path, h)
+	return r.newRoute(token, http.MethodOptions, r.Router.OPTIONS, path, h)
 }
 
 // HEAD creates a new route for HTTP HEAD
 func (r *Router) HEAD(token string, path string, h Handler) *Route {
-	return NewRoute(token, r.Router.HEAD, path, h)
+	return r.newRoute(token, http.MethodHead, r.Router.HEAD, path, h)
 }
 
 // Request represents the server request
@@ -206,15 +316,21 @@ func (r Request) Target() *http.Request {
 	return r.target
 }
 
+// Context gets the request context.
+// It is canceled when the client disconnects.
+func (r Request) Context() context.Context {
+	return r.target.Context()
+}
+
 // Params gets the request params
 func (r Request) Params() RouteParams {
 	var params httprouter.Params
 
-	if ps := context.Get(r.target, "params"); ps != nil {
-		params = ps.(httprouter.Params)
+	if ps, ok := r.target.Context().Value(paramsKey).(httprouter.Params); ok {
+		params = ps
 	}
 
-	return RouteParams{params}
+	return NewRouteParams(params)
 }
 
 // NewRequest creates an instance of Request
@@ -228,6 +344,11 @@ type Response struct {
 	contentType string
 	status      int
 	bytes       []byte
+	header      http.Header
+	cookies     []*http.Cookie
+	stream      StreamFunc
+	request     *http.Request
+	renderer    *Renderer
 }
 
 // NewResponse creates an instance of Response
@@ -270,6 +391,25 @@ func (r Response) Status() int {
 	return r.status
 }
 
+// Header gets the response headers.
+// They are written with the response, so handlers do not need the writer.
+func (r *Response) Header() http.Header {
+	if r.header == nil {
+		r.header = make(http.Header)
+	}
+	return r.header
+}
+
+// SetCookie adds a cookie to the response
+func (r *Response) SetCookie(c *http.Cookie) {
+	r.cookies = append(r.cookies, c)
+}
+
+// Cookies gets the response cookies
+func (r Response) Cookies() []*http.Cookie {
+	return r.cookies
+}
+
 // Ok creates an ok response
 func (r Response) Ok(bytes []byte) (Response, error) {
 	r.SetBytes(bytes)
@@ -307,6 +447,104 @@ func (r Response) ServerError(err error) (Response, error) {
 	return r, err
 }
 
+// Created creates a Created response with the Location header.
+// The v is sent as JSON when it is not nil.
+func (r Response) Created(location string, v interface{}) (Response, error) {
+	r.SetStatus(http.StatusCreated)
+	r.Header().Set("Location", location)
+
+	if v == nil {
+		return r, nil
+	}
+
+	return r.JSON(v)
+}
+
+// Accepted creates an Accepted response
+func (r Response) Accepted() (Response, error) {
+	r.SetStatus(http.StatusAccepted)
+	return r, nil
+}
+
+// Redirect creates a redirect response to the location.
+// It returns an IllegalStateError when the status is not 301, 302, 303, 307 or 308.
+func (r Response) Redirect(status int, location string) (Response, error) {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		err := errors.Errorf("The status %d is not a redirect status.", status)
+		return r, errors.NewIllegalStateError(err)
+	}
+
+	r.SetStatus(status)
+	r.Header().Set("Location", location)
+
+	return r, nil
+}
+
+// MovedPermanently creates a MovedPermanently (301) redirect response
+func (r Response) MovedPermanently(location string) (Response, error) {
+	return r.Redirect(http.StatusMovedPermanently, location)
+}
+
+// Found creates a Found (302) redirect response
+func (r Response) Found(location string) (Response, error) {
+	return r.Redirect(http.StatusFound, location)
+}
+
+// SeeOther creates a SeeOther (303) redirect response
+func (r Response) SeeOther(location string) (Response, error) {
+	return r.Redirect(http.StatusSeeOther, location)
+}
+
+// TemporaryRedirect creates a TemporaryRedirect (307) redirect response
+func (r Response) TemporaryRedirect(location string) (Response, error) {
+	return r.Redirect(http.StatusTemporaryRedirect, location)
+}
+
+// PermanentRedirect creates a PermanentRedirect (308) redirect response
+func (r Response) PermanentRedirect(location string) (Response, error) {
+	return r.Redirect(http.StatusPermanentRedirect, location)
+}
+
+// Unauthorized creates an Unauthorized response
+func (r Response) Unauthorized(err error) (Response, error) {
+	r.SetStatus(http.StatusUnauthorized)
+	return r, err
+}
+
+// Forbidden creates a Forbidden response
+func (r Response) Forbidden(err error) (Response, error) {
+	r.SetStatus(http.StatusForbidden)
+	return r, err
+}
+
+// Conflict creates a Conflict response
+func (r Response) Conflict(err error) (Response, error) {
+	r.SetStatus(http.StatusConflict)
+	return r, err
+}
+
+// UnprocessableEntity creates an UnprocessableEntity response
+func (r Response) UnprocessableEntity(err error) (Response, error) {
+	r.SetStatus(http.StatusUnprocessableEntity)
+	return r, err
+}
+
+// TooManyRequests creates a TooManyRequests response.
+// The Retry-After header is set in seconds when retryAfter is positive.
+func (r Response) TooManyRequests(retryAfter time.Duration, err error) (Response, error) {
+	r.SetStatus(http.StatusTooManyRequests)
+
+	if retryAfter > 0 {
+		secs := int64((retryAfter + time.Second - 1) / time.Second)
+		r.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+	}
+
+	return r, err
+}
+
 // BadRequest creates a BadRequest response
 func BadRequest(err error) (Response, error) {
 	r := Response{}
@@ -330,91 +568,208 @@ func (r *Router) applyErrorHandler(h HandlerFunc) HandlerFunc {
 
 func (r *Router) responseMiddleware(h HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
-		resp, err := h(NewResponse(w), NewRequest(req))
+		resp := NewResponse(w)
+		resp.request = req
+		resp.renderer = r.renderer
+
+		resp, err := h(resp, NewRequest(req))
 
 		s := resp.Status()
 
+		if err != nil && (s == 0 || s >= http.StatusInternalServerError) {
+			r.log().Error("Request failed",
+				"method", req.Method, "path", req.URL.Path,
+				"request_id", RequestID(req), "error", err)
+		}
+
 		// Ensures that the Internal Server can be defined without response body
+		// A body set by the error handler is kept
 		if (s == 0 && err != nil) || s == http.StatusInternalServerError {
-			if err != nil && SupressError == false {
+			if err != nil && SupressError == false && len(resp.Bytes()) == 0 {
 				bytes := []byte(err.Error())
 				resp.SetBytes(bytes)
 			}
+
+			s = http.StatusInternalServerError
 		} else if s == 0 {
 			s = http.StatusOK
 		}
 
 		w.Header().Set("Content-Type", resp.ContentType())
+
+		for k, vs := range resp.header {
+			w.Header()[k] = vs
+		}
+
+		for _, c := range resp.cookies {
+			http.SetCookie(w, c)
+		}
+
 		w.WriteHeader(s)
 
+		if resp.stream != nil && err == nil {
+			if err := writeStream(w, resp.stream); err != nil {
+				r.log().Error("Streaming failed",
+					"method", req.Method, "path", req.URL.Path,
+					"request_id", RequestID(req), "error", err)
+			}
+			return
+		}
+
 		if bytes := resp.Bytes(); len(bytes) > 0 {
 			w.Write(resp.Bytes())
 		}
 	}
 }
 
+// routeMiddlewares gets the middlewares for a route.
+// A route on the middlewares map uses only the mapped middlewares.
+// Otherwise, it uses all middlewares that are not silent.
+// The middlewares of the route groups and of the route are added after
+// them, and the middlewares skipped by the route are removed.
+func (r *Router) routeMiddlewares(route *Route) []Middleware {
+	var chain []Middleware
+
+	if middlewareTokens, ok := r.middlewaresMap[route.Token]; ok {
+		for _, middlewareToken := range middlewareTokens {
+			for _, middleware := range r.middlewares {
+				if middleware.Token == middlewareToken {
+					chain = append(chain, middleware)
+				}
+			}
+		}
+	} else {
+		for _, middleware := range r.middlewares {
+			if middleware.Silent == false {
+				chain = append(chain, middleware)
+			}
+		}
+	}
+
+	if route.group != nil {
+		chain = append(chain, route.group.Middlewares()...)
+	}
+
+	chain = append(chain, route.middlewares...)
+
+	if len(route.skip) == 0 {
+		return chain
+	}
+
+	filtered := chain[:0]
+
+	for _, middleware := range chain {
+		if !route.skips(middleware.Token) {
+			filtered = append(filtered, middleware)
+		}
+	}
+
+	return filtered
+}
+
 // Start configures all necessary steps for each route.
-// The router starts the middlewares chain with the context.ClearHandler.
-// It is responsible to clear all data in the request context.
+// It runs once, the next calls do nothing.
+// The route params and token are put to the request context.
 // After, it configures specific middlewares for a route or adds all.
 // So, the router adds the error handle as the last handler in the chain.
+// The unmatched requests are answered by the fallback handlers.
 func (r *Router) Start() *Router {
-	for _, routes := range r.routes {
-		for _, route := range routes {
-			middlewares := r.middlewares
+	if r.started {
+		return r
+	}
 
-			middlewaresMap := r.middlewaresMap
+	r.started = true
 
-			chain := alice.New(context.ClearHandler)
+	if r.openAPIPath != "" {
+		r.addOpenAPIRoute()
+	}
 
-			if middlewareTokens, ok := middlewaresMap[route.Token]; ok {
-				for _, middlewareToken := range middlewareTokens {
-					for _, middleware := range middlewares {
-						if middleware.Token == middlewareToken {
-							chain = chain.Append(middleware.Constructor)
-						}
-					}
-				}
-			} else {
-				for _, middleware := range middlewares {
-					if middleware.Silent == false {
-						chain = chain.Append(middleware.Constructor)
-					}
-				}
+	for _, routes := range r.routes {
+		for _, route := range routes {
+			if route.mounted != nil {
+				r.mount(route)
+				continue
 			}
 
-			var handler http.HandlerFunc
+			handler := r.chain(r.routeMiddlewares(route), route.Handler)
 
-			if r.errorHandler != nil {
-				handler = r.responseMiddleware(
-					r.applyErrorHandler(route.Handler))
-			} else {
-				handler = r.responseMiddleware(route.Handler)
-			}
-
-			route.Method(route.Path, HTTPRouterWrapHandler(chain.Then(handler)))
+			route.Method(route.Path, HTTPRouterWrapHandler(
+				routeTokenHandler(route.Token,
+					r.metrics.Handler(route.Token, handler))))
 		}
 	}
 
+	r.Router.HandleOPTIONS = false
+	r.Router.HandleMethodNotAllowed = false
+	r.Router.NotFound = r.fallbackHandler()
+
 	return r
 }
 
-// Route represents a route
+// chain creates the handler running the middlewares, the error handler and h
+func (r *Router) chain(middlewares []Middleware, h HandlerFunc) http.Handler {
+	chain := alice.New()
+
+	for _, middleware := range middlewares {
+		chain = chain.Append(middleware.Constructor)
+	}
+
+	if r.errorHandler != nil {
+		h = r.applyErrorHandler(h)
+	}
+
+	return chain.Then(r.responseMiddleware(h))
+}
+
+// Route represents a route.
+// The MethodName is set by the router methods, like GET and POST.
+// The Doc is used on the OpenAPI document.
 type Route struct {
-	Token   string
-	Method  HTTPMethod
-	Path    string
-	Handler HandlerFunc
+	Token      string
+	Method     HTTPMethod
+	MethodName string
+	Path       string
+	Handler    HandlerFunc
+	Doc        RouteDoc
+
+	group       *Group
+	middlewares []Middleware
+	skip        []string
+	mounted     http.Handler
+}
+
+// Use adds a middleware to the route, after the globals and the group ones
+func (r *Route) Use(token string, constructor alice.Constructor) *Route {
+	r.middlewares = append(r.middlewares, Middleware{token, constructor, false})
+	return r
+}
+
+// Skip removes the middlewares with the tokens from the route,
+// like the globals or the group ones
+func (r *Route) Skip(tokens ...string) *Route {
+	r.skip = append(r.skip, tokens...)
+	return r
+}
+
+// skips verifies if the route skips the middleware token
+func (r *Route) skips(token string) bool {
+	for _, t := range r.skip {
+		if t == token {
+			return true
+		}
+	}
+
+	return false
 }
 
 // NewRoute creates an instance of *Route using a struct
 func NewRoute(token string, method HTTPMethod, path string, handler Handler) *Route {
-	return &Route{token, method, path, handler.ServeHTTP}
+	return &Route{Token: token, Method: method, Path: path, Handler: handler.ServeHTTP}
 }
 
 // NewRouteFunc creates an instance of *Route using a func
 func NewRouteFunc(token string, method HTTPMethod, path string, handler HandlerFunc) *Route {
-	return &Route{token, method, path, handler}
+	return &Route{Token: token, Method: method, Path: path, Handler: handler}
 }
 
 // Middleware represents a middleware
@@ -472,12 +827,19 @@ type ErrorHandler func(HandlerFunc) HandlerFunc
 
 // ErrorMessage represents errors sent as response
 type ErrorMessage struct {
-	Message []string `json:"errors"`
+	Message   []string `json:"errors"`
+	RequestID string   `json:"request_id,omitempty"`
 }
 
 // NewErrorMessage creates a ErrorMessages instance
 func NewErrorMessage(message ...string) ErrorMessage {
-	return ErrorMessage{message}
+	return ErrorMessage{Message: message}
+}
+
+// WithRequestID sets the request id of the error message
+func (e ErrorMessage) WithRequestID(id string) ErrorMessage {
+	e.RequestID = id
+	return e
 }
 
 // PanicRecoverMiddleware recovers a panic error
@@ -499,6 +861,32 @@ func PanicRecoverMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// NewPanicRecoverMiddleware creates a middleware that recovers a panic
+// and logs it with the stack trace on the l logger
+func NewPanicRecoverMiddleware(l *logger.Logger) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if v := recover(); v != nil {
+					stack := debug.Stack()
+
+					l.Error("Panic recovered",
+						"method", r.Method, "path", r.URL.Path, "request_id", RequestID(r),
+						"panic", fmt.Sprint(v), "stack", string(stack))
+
+					w.WriteHeader(http.StatusInternalServerError)
+
+					if SupressError == false {
+						w.Write(stack)
+					}
+				}
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // JSONContentTypeMiddleware forces application/json Content-Type
 func JSONContentTypeMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,202 @@
+package knife
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RouteMetrics represents the metrics of a route
+type RouteMetrics struct {
+	Token        string         `json:"token"`
+	Count        uint64         `json:"count"`
+	ClientErrors uint64         `json:"client_errors"`
+	ServerErrors uint64         `json:"server_errors"`
+	Bytes        uint64         `json:"bytes"`
+	TotalMs      float64        `json:"total_ms"`
+	MaxMs        float64        `json:"max_ms"`
+	Statuses     map[int]uint64 `json:"statuses"`
+}
+
+// Metrics collects the metrics of the routes
+type Metrics struct {
+	mu     sync.Mutex
+	routes map[string]*RouteMetrics
+}
+
+// NewMetrics creates an instance of *Metrics
+func NewMetrics() *Metrics {
+	return &Metrics{routes: make(map[string]*RouteMetrics)}
+}
+
+// Observe records a response for the route token
+func (m *Metrics) Observe(token string, status int, size int, d time.Duration) {
+	ms := float64(d) / float64(time.Millisecond)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rm, ok := m.routes[token]
+
+	if !ok {
+		rm = &RouteMetrics{Token: token, Statuses: make(map[int]uint64)}
+		m.routes[token] = rm
+	}
+
+	rm.Count++
+	rm.Bytes += uint64(size)
+	rm.TotalMs += ms
+	rm.Statuses[status]++
+
+	if ms > rm.MaxMs {
+		rm.MaxMs = ms
+	}
+
+	switch {
+	case status >= 500:
+		rm.ServerErrors++
+	case status >= 400:
+		rm.ClientErrors++
+	}
+}
+
+// Snapshot gets a copy of the metrics sorted by route token
+func (m *Metrics) Snapshot() []RouteMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make([]RouteMetrics, 0, len(m.routes))
+
+	for _, rm := range m.routes {
+		c := *rm
+		c.Statuses = make(map[int]uint64, len(rm.Statuses))
+
+		for k, v := range rm.Statuses {
+			c.Statuses[k] = v
+		}
+
+		snapshot = append(snapshot, c)
+	}
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].Token < snapshot[j].Token
+	})
+
+	return snapshot
+}
+
+// Handler wraps h to record the metrics for the route token
+func (m *Metrics) Handler(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		begin := time.Now()
+		rw := NewResponseWriter(w)
+
+		h.ServeHTTP(rw, r)
+
+		m.Observe(token, rw.Status(), rw.Size(), time.Since(begin))
+	})
+}
+
+// ServeHTTP serves the metrics snapshot as JSON
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bytes, err := MarshalJSON(m.Snapshot())
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(bytes)
+}
+
+// ResponseWriter wraps a http.ResponseWriter
+// to record the status and the size of the response
+type ResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+	size   int
+}
+
+// NewResponseWriter creates an instance of *ResponseWriter.
+// When w is already a *ResponseWriter, it is returned.
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	if rw, ok := w.(*ResponseWriter); ok {
+		return rw
+	}
+
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *ResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+
+	return n, err
+}
+
+// Flush implements http.Flusher
+func (w *ResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, so the websocket upgrades work
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	conn, rw, err := h.Hijack()
+
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+
+	return conn, rw, err
+}
+
+// Unwrap gets the wrapped http.ResponseWriter, used by http.ResponseController
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status gets the response status
+func (w *ResponseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
+
+// Size gets the number of bytes written
+func (w *ResponseWriter) Size() int {
+	return w.size
+}
+
+// Written verifies if the header was written
+func (w *ResponseWriter) Written() bool {
+	return w.status != 0
+}
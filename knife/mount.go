@@ -0,0 +1,87 @@
+package knife
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/justinas/alice"
+)
+
+// MountMethod is the method name of the mounted handlers on the route table
+const MountMethod = "*"
+
+// mountParam is the catch-all param of the mounted handlers
+const mountParam = "*path"
+
+// Mount serves h under the prefix for all methods, like
+// Mount("/debug/pprof", http.DefaultServeMux). The prefix is stripped
+// from the request path. The route token is the prefix with dots, like
+// "debug.pprof", and the route runs the global middlewares.
+// A mounted *Router is started with the router, so an application
+// can be assembled from routers built apart.
+func (r *Router) Mount(prefix string, h http.Handler) *Route {
+	route := newMountRoute(prefix, h)
+
+	if _, ok := r.route(route.Token); ok {
+		m := "knife: many registrations for route '%s'."
+		panic(fmt.Sprintf(m, route.Token))
+	}
+
+	r.routes[""] = append(r.routes[""], route)
+
+	return route
+}
+
+// Mount serves h under the prefix of the group for all methods.
+// The route also runs the group middlewares.
+func (g *Group) Mount(prefix string, h http.Handler) *Route {
+	route := newMountRoute(prefix, h)
+	g.AddRoutes(route)
+	return route
+}
+
+// newMountRoute creates the route of a mounted handler
+func newMountRoute(prefix string, h http.Handler) *Route {
+	prefix = strings.Trim(prefix, "/")
+
+	if prefix == "" {
+		panic("knife: the mount prefix can not be empty.")
+	}
+
+	return &Route{
+		Token:      strings.Replace(prefix, "/", ".", -1),
+		MethodName: MountMethod,
+		Path:       "/" + prefix + "/" + mountParam,
+		mounted:    h,
+	}
+}
+
+// mount registers the mounted handler of the route for all methods
+func (r *Router) mount(route *Route) {
+	if sub, ok := route.mounted.(*Router); ok {
+		if sub.Logger() == nil {
+			sub.SetLogger(r.logger)
+		}
+
+		sub.Start()
+	}
+
+	prefix := strings.TrimSuffix(route.Path, "/"+mountParam)
+
+	chain := alice.New()
+
+	for _, middleware := range r.routeMiddlewares(route) {
+		chain = chain.Append(middleware.Constructor)
+	}
+
+	handler := chain.Then(http.StripPrefix(prefix, route.mounted))
+
+	handle := HTTPRouterWrapHandler(
+		routeTokenHandler(route.Token,
+			r.metrics.Handler(route.Token, handler)))
+
+	for _, method := range fallbackMethods {
+		r.Router.Handle(method, route.Path, handle)
+	}
+}
@@ -0,0 +1,467 @@
+package knife
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPIVersion is the version of the generated documents
+const OpenAPIVersion = "3.0.3"
+
+// OpenAPIToken is the token of the route serving the OpenAPI document
+const OpenAPIToken = "openapi"
+
+// RouteDoc represents the OpenAPI metadata of a route
+type RouteDoc struct {
+	Summary string
+	Tags    []string
+
+	// The request type, read for the body and the
+	// query, path and header params, like the Binder
+	Request interface{}
+
+	// The response types by status, nil for no body
+	Responses map[int]interface{}
+}
+
+// Describe sets the summary and the tags of the route.
+// The tags default to the token of the route group.
+func (r *Route) Describe(summary string, tags ...string) *Route {
+	r.Doc.Summary = summary
+	r.Doc.Tags = tags
+	return r
+}
+
+// Accepts sets the request type of the route, like Accepts(CreateUser{})
+func (r *Route) Accepts(v interface{}) *Route {
+	r.Doc.Request = v
+	return r
+}
+
+// Returns adds a response type of the route, like Returns(200, []User{}).
+// The v is nil for a response without body.
+func (r *Route) Returns(status int, v interface{}) *Route {
+	if r.Doc.Responses == nil {
+		r.Doc.Responses = make(map[int]interface{})
+	}
+
+	r.Doc.Responses[status] = v
+
+	return r
+}
+
+// OpenAPIInfo represents the info of the OpenAPI document
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPI represents an OpenAPI 3 document
+type OpenAPI struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       OpenAPIInfo                     `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+// Components represents the reusable schemas of the document
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Operation represents an OpenAPI operation
+type Operation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  []Parameter                `json:"parameters,omitempty"`
+	RequestBody *RequestBody               `json:"requestBody,omitempty"`
+	Responses   map[string]OperationResult `json:"responses"`
+}
+
+// Parameter represents an OpenAPI parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody represents an OpenAPI request body
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// OperationResult represents an OpenAPI response
+type OperationResult struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType represents the schema of a media type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema represents an OpenAPI schema
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// SetOpenAPI sets the path serving the OpenAPI document, like "/openapi.json".
+// The document is generated when the router starts, and served by
+// the route OpenAPIToken, which runs the global middlewares.
+func (r *Router) SetOpenAPI(path string, info OpenAPIInfo) {
+	r.openAPIPath = path
+	r.openAPIInfo = info
+}
+
+// OpenAPI generates the OpenAPI document of the routes.
+// The routes created without the router methods are skipped,
+// since their HTTP method is unknown, as the mounted handlers.
+func (r *Router) OpenAPI(info OpenAPIInfo) OpenAPI {
+	g := &schemaGenerator{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+
+	doc := OpenAPI{
+		OpenAPI: OpenAPIVersion,
+		Info:    info,
+		Paths:   make(map[string]map[string]Operation),
+	}
+
+	for _, routes := range r.routes {
+		for _, route := range routes {
+			if route.MethodName == "" || route.mounted != nil || route.Token == OpenAPIToken {
+				continue
+			}
+
+			path, params := openAPIPath(route.Path)
+
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = make(map[string]Operation)
+			}
+
+			doc.Paths[path][strings.ToLower(route.MethodName)] = g.operation(route, params)
+		}
+	}
+
+	doc.Components.Schemas = g.schemas
+
+	return doc
+}
+
+// addOpenAPIRoute adds the route serving the OpenAPI document.
+// It panics when the token or the path is already used.
+func (r *Router) addOpenAPIRoute() {
+	if _, ok := r.route(OpenAPIToken); ok {
+		m := "knife: many registrations for route '%s'."
+		panic(fmt.Sprintf(m, OpenAPIToken))
+	}
+
+	for _, routes := range r.routes {
+		for _, route := range routes {
+			if route.Path == r.openAPIPath && (route.MethodName == http.MethodGet || route.mounted != nil) {
+				m := "knife: the OpenAPI path '%s' is used by the route '%s'."
+				panic(fmt.Sprintf(m, r.openAPIPath, route.Token))
+			}
+		}
+	}
+
+	route := NewRouteFunc(OpenAPIToken, r.Router.GET, r.openAPIPath, r.openAPIHandler())
+	route.MethodName = http.MethodGet
+
+	r.routes[""] = append(r.routes[""], route)
+}
+
+// openAPIHandler generates the OpenAPI document once and serves it
+func (r *Router) openAPIHandler() HandlerFunc {
+	bytes, err := MarshalJSON(r.OpenAPI(r.openAPIInfo))
+
+	if err != nil {
+		panic(err)
+	}
+
+	return func(resp Response, req *Request) (Response, error) {
+		resp.SetContentType("application/json; charset=utf-8")
+		return resp.Ok(bytes)
+	}
+}
+
+// openAPIPath converts the route path, like "/users/:id" to "/users/{id}",
+// and gets the names of the path params
+func openAPIPath(path string) (string, []string) {
+	var params []string
+
+	segments := strings.Split(path, "/")
+
+	for i, s := range segments {
+		if len(s) > 0 && (s[0] == ':' || s[0] == '*') {
+			params = append(params, s[1:])
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/"), params
+}
+
+// schemaGenerator generates the schemas of the Go types.
+// The structs are put to the components and referenced.
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+// operation creates the operation of a route
+func (g *schemaGenerator) operation(route *Route, pathParams []string) Operation {
+	op := Operation{
+		OperationID: route.Token,
+		Summary:     route.Doc.Summary,
+		Tags:        route.Doc.Tags,
+		Responses:   make(map[string]OperationResult),
+	}
+
+	if len(op.Tags) == 0 && route.group != nil {
+		op.Tags = []string{route.group.Token()}
+	}
+
+	typed := make(map[string]bool)
+
+	if route.Doc.Request != nil {
+		t := indirectType(reflect.TypeOf(route.Doc.Request))
+
+		if t.Kind() == reflect.Struct {
+			for _, in := range []string{"path", "query", "header"} {
+				for _, p := range g.parameters(t, in) {
+					op.Parameters = append(op.Parameters, p)
+
+					if p.In == "path" {
+						typed[p.Name] = true
+					}
+				}
+			}
+		}
+
+		if body := g.body(t); body != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{"application/json": {body}},
+			}
+		}
+	}
+
+	for _, name := range pathParams {
+		if !typed[name] {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+	}
+
+	for status, v := range route.Doc.Responses {
+		result := OperationResult{Description: http.StatusText(status)}
+
+		if v != nil {
+			result.Content = map[string]MediaType{"application/json": {g.schema(reflect.TypeOf(v))}}
+		}
+
+		op.Responses[strconv.Itoa(status)] = result
+	}
+
+	if len(op.Responses) == 0 {
+		op.Responses["200"] = OperationResult{Description: http.StatusText(http.StatusOK)}
+	}
+
+	return op
+}
+
+// parameters gets the params of the struct fields with the tag of in
+func (g *schemaGenerator) parameters(t reflect.Type, in string) []Parameter {
+	var params []Parameter
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		name := strings.Split(sf.Tag.Get(in), ",")[0]
+
+		if sf.PkgPath != "" || name == "" || name == "-" {
+			if sf.Anonymous && indirectType(sf.Type).Kind() == reflect.Struct {
+				params = append(params, g.parameters(indirectType(sf.Type), in)...)
+			}
+			continue
+		}
+
+		params = append(params, Parameter{
+			Name:     name,
+			In:       in,
+			Required: in == "path" || hasRule(sf, "required"),
+			Schema:   g.schema(sf.Type),
+		})
+	}
+
+	return params
+}
+
+// body gets the schema of the request body.
+// It is nil when all fields are params.
+func (g *schemaGenerator) body(t reflect.Type) *Schema {
+	if t.Kind() != reflect.Struct || isScalarStruct(t) {
+		return g.schema(t)
+	}
+
+	for _, sf := range bodyFields(t) {
+		if sf.PkgPath == "" {
+			return g.schema(t)
+		}
+	}
+
+	return nil
+}
+
+// schema gets the schema of a Go type
+func (g *schemaGenerator) schema(t reflect.Type) *Schema {
+	nullable := t.Kind() == reflect.Ptr
+	t = indirectType(t)
+
+	var s *Schema
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		s = &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && isScalarStruct(t):
+		s = &Schema{Type: "string"}
+	case t.Kind() == reflect.Struct:
+		s = &Schema{Ref: "#/components/schemas/" + g.component(t)}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		s = &Schema{Type: "string", Format: "byte"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s = &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		s = &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case t.Kind() == reflect.String:
+		s = &Schema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		s = &Schema{Type: "boolean"}
+	case t.Kind() == reflect.Int64 || t.Kind() == reflect.Uint64:
+		s = &Schema{Type: "integer", Format: "int64"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uintptr:
+		s = &Schema{Type: "integer", Format: "int32"}
+	case t.Kind() == reflect.Float32:
+		s = &Schema{Type: "number", Format: "float"}
+	case t.Kind() == reflect.Float64:
+		s = &Schema{Type: "number", Format: "double"}
+	default:
+		s = &Schema{}
+	}
+
+	if nullable && s.Ref == "" {
+		s.Nullable = true
+	}
+
+	return s
+}
+
+// component puts the struct schema to the components and gets its name
+func (g *schemaGenerator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	base := t.Name()
+
+	if base == "" {
+		base = "Object"
+	}
+
+	name := base
+
+	for i := 2; g.schemas[name] != nil; i++ {
+		name = base + strconv.Itoa(i)
+	}
+
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	// registered before the fields, so recursive types are referenced
+	g.names[t] = name
+	g.schemas[name] = s
+
+	for _, sf := range bodyFields(t) {
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		name := sf.Name
+		parts := strings.Split(sf.Tag.Get("json"), ",")
+
+		if parts[0] != "" {
+			name = parts[0]
+		}
+
+		s.Properties[name] = g.schema(sf.Type)
+
+		if hasRule(sf, "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	sort.Strings(s.Required)
+
+	return g.names[t]
+}
+
+// bodyFields gets the fields encoded on the JSON body.
+// The embedded structs are flattened, and the fields that
+// are params or are skipped by the json tag are dropped.
+func bodyFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+
+		if tag == "-" || sf.Tag.Get("path") != "" || sf.Tag.Get("query") != "" || sf.Tag.Get("header") != "" {
+			continue
+		}
+
+		if sf.Anonymous && tag == "" && indirectType(sf.Type).Kind() == reflect.Struct {
+			fields = append(fields, bodyFields(indirectType(sf.Type))...)
+			continue
+		}
+
+		fields = append(fields, sf)
+	}
+
+	return fields
+}
+
+// hasRule verifies if the validate tag of the field has the rule
+func hasRule(sf reflect.StructField, rule string) bool {
+	for _, r := range strings.Split(sf.Tag.Get("validate"), ",") {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+
+	return false
+}
+
+// indirectType dereferences the pointer types
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
@@ -0,0 +1,112 @@
+package knife
+
+import (
+	"net/http"
+
+	"github.com/tralus/koala/errors"
+	"github.com/tralus/koala/validate"
+)
+
+// ProblemContentType is the media type of the problem responses
+const ProblemContentType = "application/problem+json"
+
+// Problem represents an RFC 7807 problem response
+type Problem struct {
+	Type      string               `json:"type"`
+	Title     string               `json:"title"`
+	Status    int                  `json:"status"`
+	Detail    string               `json:"detail,omitempty"`
+	Instance  string               `json:"instance,omitempty"`
+	RequestID string               `json:"request_id,omitempty"`
+	Errors    validate.FieldErrors `json:"errors,omitempty"`
+}
+
+// NewProblemErrorHandler creates an ErrorHandler answering the errors
+// as application/problem+json. The koala errors are mapped as:
+//
+//	NotFoundError, errors.NotFounder     404
+//	IllegalArgumentError, UnMarshalError 400
+//	NotAuthorizedError                   401
+//	ForbiddenError                       403
+//	RelationshipError                    409
+//	NotAcceptableError                   406
+//	UnsupportedMediaTypeError            415
+//	IllegalStateError and others         500
+//
+// Other errors keep the status set by the handler, or 500.
+// The details of the 5xx errors and of the NotFounder errors, like the
+// sqlxtpl.EmptyResultDataError of the database, are hidden unless debug,
+// usually set with the Config.Debug of the application.
+func NewProblemErrorHandler(debug bool) ErrorHandler {
+	return func(h HandlerFunc) HandlerFunc {
+		return func(resp Response, req *Request) (Response, error) {
+			resp, err := h(resp, req)
+
+			if err == nil {
+				return resp, nil
+			}
+
+			p := NewProblem(err, resp.Status(), debug)
+			p.Instance = req.URL().Path
+			p.RequestID = req.ID()
+
+			bytes, merr := MarshalJSON(p)
+
+			if merr != nil {
+				return resp, err
+			}
+
+			resp.SetStatus(p.Status)
+			resp.SetContentType(ProblemContentType)
+			resp.SetBytes(bytes)
+
+			return resp, err
+		}
+	}
+}
+
+// NewProblem creates a Problem for the error.
+// The status is used for the errors that are not mapped, when not zero.
+func NewProblem(err error, status int, debug bool) Problem {
+	hidden := false
+
+	switch {
+	case errors.IsNotFoundError(err):
+		_, known := errors.Cause(err).(errors.NotFoundError)
+		status, hidden = http.StatusNotFound, !known
+	case errors.IsIllegalArgumentError(err), IsUnMarshalError(errors.Cause(err)):
+		status = http.StatusBadRequest
+	case errors.IsNotAuthorizedError(err):
+		status = http.StatusUnauthorized
+	case errors.IsForbiddenError(err):
+		status = http.StatusForbidden
+	case errors.IsRelationshipError(err):
+		status = http.StatusConflict
+	case IsNotAcceptableError(err):
+		status = http.StatusNotAcceptable
+	case IsUnsupportedMediaTypeError(err):
+		status = http.StatusUnsupportedMediaType
+	case errors.IsIllegalStateError(err):
+		status = http.StatusInternalServerError
+	case status == 0:
+		status = http.StatusInternalServerError
+	}
+
+	p := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+	}
+
+	if fe, ok := validate.GetFieldErrors(err); ok {
+		p.Detail = "The request has invalid fields."
+		p.Errors = fe
+	}
+
+	if (hidden || status >= http.StatusInternalServerError) && !debug {
+		p.Detail = ""
+	}
+
+	return p
+}
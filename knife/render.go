@@ -0,0 +1,252 @@
+package knife
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tralus/koala/errors"
+)
+
+// Renderer renders the html/template files of a directory.
+// The files are named by their path, like "users/show.html", and are
+// organized as:
+//
+//	layouts/   the layouts, like "layouts/base.html"
+//	partials/  the partials, like {{template "partials/nav.html" .}}
+//	others     the pages
+//
+// Each page is parsed with all layouts and partials, and rendered by
+// the layout, which includes the blocks defined by the page.
+// The templates are parsed on each render on debug, and cached otherwise.
+type Renderer struct {
+	fsys  fs.FS
+	debug bool
+
+	// The layout rendering the pages, "layouts/base.html" by default.
+	// The pages are rendered alone when it does not exist.
+	Layout string
+
+	// The extension of the template files, ".html" by default
+	Ext string
+
+	mu        sync.RWMutex
+	funcs     template.FuncMap
+	templates map[string]*template.Template
+}
+
+// NewRenderer creates a *Renderer instance for the directory.
+// The debug is usually the Config.Debug of the application.
+func NewRenderer(dir string, debug bool) *Renderer {
+	return NewRendererFS(os.DirFS(dir), debug)
+}
+
+// NewRendererFS creates a *Renderer instance for the fsys
+func NewRendererFS(fsys fs.FS, debug bool) *Renderer {
+	return &Renderer{
+		fsys:      fsys,
+		debug:     debug,
+		Layout:    "layouts/base.html",
+		Ext:       ".html",
+		funcs:     make(template.FuncMap),
+		templates: make(map[string]*template.Template),
+	}
+}
+
+// Funcs adds functions shared by all templates.
+// The cached templates are discarded.
+func (r *Renderer) Funcs(funcs template.FuncMap) *Renderer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name, fn := range funcs {
+		r.funcs[name] = fn
+	}
+
+	r.templates = make(map[string]*template.Template)
+
+	return r
+}
+
+// Load parses all pages, so the template errors are found on the start.
+// Nothing is cached on debug.
+func (r *Renderer) Load() error {
+	pages, _, err := r.files()
+
+	if err != nil {
+		return err
+	}
+
+	for _, page := range pages {
+		if _, err := r.template(page); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Render renders the page with the data on w.
+// The name can omit the extension, like "users/show".
+// Nothing is written when the render fails.
+func (r *Renderer) Render(w io.Writer, name string, data interface{}) error {
+	if path.Ext(name) == "" {
+		name += r.Ext
+	}
+
+	t, err := r.template(name)
+
+	if err != nil {
+		return err
+	}
+
+	entry := name
+
+	if t.Lookup(r.Layout) != nil && !r.isShared(name) {
+		entry = r.Layout
+	}
+
+	var buf bytes.Buffer
+
+	if err := t.ExecuteTemplate(&buf, entry, data); err != nil {
+		return errors.NewIllegalStateError(errors.Wrap(err, "It was not possible to render the template."))
+	}
+
+	_, err = buf.WriteTo(w)
+
+	return err
+}
+
+// template gets the template set of the page, parsing it when needed
+func (r *Renderer) template(name string) (*template.Template, error) {
+	if !r.debug {
+		r.mu.RLock()
+		t, ok := r.templates[name]
+		r.mu.RUnlock()
+
+		if ok {
+			return t, nil
+		}
+	}
+
+	t, err := r.parse(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.debug {
+		r.mu.Lock()
+		r.templates[name] = t
+		r.mu.Unlock()
+	}
+
+	return t, nil
+}
+
+// parse parses the page with the layouts and the partials
+func (r *Renderer) parse(name string) (*template.Template, error) {
+	_, shared, err := r.files()
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	t := template.New("knife").Funcs(r.funcs)
+	r.mu.RUnlock()
+
+	// the page is parsed after the shared files, so its blocks win
+	files := shared
+
+	if !r.isShared(name) {
+		files = append(files, name)
+	}
+
+	for _, file := range files {
+		data, err := fs.ReadFile(r.fsys, file)
+
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, errors.NewIllegalStateError(errors.Errorf("The template %s was not found.", file))
+			}
+			return nil, err
+		}
+
+		if _, err := t.New(file).Parse(string(data)); err != nil {
+			return nil, errors.NewIllegalStateError(errors.Wrap(err, "It was not possible to parse the template."))
+		}
+	}
+
+	return t, nil
+}
+
+// files gets the pages and the shared files, the layouts and the partials
+func (r *Renderer) files() ([]string, []string, error) {
+	var pages, shared []string
+
+	err := fs.WalkDir(r.fsys, ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || path.Ext(file) != r.Ext {
+			return nil
+		}
+
+		if r.isShared(file) {
+			shared = append(shared, file)
+		} else {
+			pages = append(pages, file)
+		}
+
+		return nil
+	})
+
+	sort.Strings(pages)
+	sort.Strings(shared)
+
+	return pages, shared, err
+}
+
+// isShared verifies if the file is a layout or a partial
+func (r *Renderer) isShared(file string) bool {
+	return strings.HasPrefix(file, "layouts/") || strings.HasPrefix(file, "partials/")
+}
+
+// SetRenderer defines the renderer used by Response.Render.
+// The templates get the "url" function, building the route paths
+// like {{url "users.show" "id" .ID}}.
+func (r *Router) SetRenderer(rd *Renderer) {
+	rd.Funcs(template.FuncMap{"url": r.URL})
+	r.renderer = rd
+}
+
+// Renderer gets the renderer of the router, nil when it was not defined
+func (r *Router) Renderer() *Renderer {
+	return r.renderer
+}
+
+// Render creates a text/html response rendering the page with the data.
+// It requires a renderer on the router.
+func (r Response) Render(name string, data interface{}) (Response, error) {
+	if r.renderer == nil {
+		return r, errors.NewIllegalStateError(errors.New("The router has no renderer."))
+	}
+
+	var buf bytes.Buffer
+
+	if err := r.renderer.Render(&buf, name, data); err != nil {
+		return r, err
+	}
+
+	r.SetContentType("text/html; charset=utf-8")
+
+	return r.Ok(buf.Bytes())
+}
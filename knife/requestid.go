@@ -0,0 +1,51 @@
+package knife
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/tralus/koala/context"
+)
+
+// RequestIDHeader is the header that carries the request id
+const RequestIDHeader = "X-Request-ID"
+
+// NewRequestID generates a random request id with 32 hex chars
+func NewRequestID() string {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// RequestIDMiddleware reads the X-Request-ID header or generates a new id.
+// The id is put to the request context and echoed on the response header.
+// The sqlxtpl queries read it with SqlxTpl.WithContext.
+// Invalid ids received from the clients are replaced.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+
+		if !context.ValidRequestID(id) {
+			id = NewRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+
+		next.ServeHTTP(w, r.WithContext(context.WithRequestID(r.Context(), id)))
+	})
+}
+
+// RequestID gets the request id from the request context
+func RequestID(r *http.Request) string {
+	return context.RequestID(r.Context())
+}
+
+// ID gets the request id
+func (r Request) ID() string {
+	return RequestID(r.target)
+}
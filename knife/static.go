@@ -0,0 +1,313 @@
+package knife
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultImmutableRegex matches the fingerprinted file names, like "main.3f2a9c1b.js"
+var DefaultImmutableRegex = regexp.MustCompile(`[.-][0-9a-f]{8,}\.[0-9A-Za-z]+$`)
+
+// StaticConfig represents the settings of the static handler
+type StaticConfig struct {
+	// The file served for the directories, "index.html" by default
+	Index string
+
+	// Serves the index for the unknown paths without file extension,
+	// so the routes of a single-page app are handled by the browser.
+	// As a router fallback, the paths under the route groups, like
+	// "/api/usres", get the NotFound handler. Set Exclude for the other
+	// API paths, or when the handler is mounted.
+	SPA bool
+
+	// Path prefixes without SPA fallback, like "/api/".
+	// The paths are relative to the handler when it is mounted.
+	Exclude []string
+
+	// Matches the fingerprinted files, cached as immutable.
+	// It is the DefaultImmutableRegex when nil.
+	Immutable *regexp.Regexp
+
+	// Cache max age of the other files, revalidated on each request when zero
+	MaxAge time.Duration
+}
+
+// staticHandler serves the files of a fs.FS
+type staticHandler struct {
+	fsys   fs.FS
+	config StaticConfig
+
+	mu    sync.Mutex
+	etags map[string]staticETag
+}
+
+// staticETag represents the cached ETag of a file version
+type staticETag struct {
+	modTime time.Time
+	size    int64
+	etag    string
+}
+
+// NewStaticHandler creates a handler serving the files of fsys.
+// It sets strong ETags, answers the conditional and range requests,
+// and serves the .br and .gz variants of a file when the client
+// accepts them. The hidden files are not served.
+func NewStaticHandler(fsys fs.FS, c StaticConfig) http.Handler {
+	if c.Index == "" {
+		c.Index = "index.html"
+	}
+
+	if c.Immutable == nil {
+		c.Immutable = DefaultImmutableRegex
+	}
+
+	return &staticHandler{fsys: fsys, config: c, etags: make(map[string]staticETag)}
+}
+
+// NewStaticDirHandler creates a handler serving the files of the directory
+func NewStaticDirHandler(dir string, c StaticConfig) http.Handler {
+	return NewStaticHandler(os.DirFS(dir), c)
+}
+
+// ServeHTTP implements http.Handler
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, ok := h.resolve(r.URL.Path)
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.serve(w, r, name)
+}
+
+// Matches implements FallbackMatcher, so the router answers the
+// paths without files and the methods other than GET and HEAD
+// with its NotFound handler
+func (h *staticHandler) Matches(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	_, ok := h.resolve(r.URL.Path)
+	return ok
+}
+
+// resolve gets the file served for the path.
+// It returns false when no file is served.
+func (h *staticHandler) resolve(urlPath string) (string, bool) {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+
+	if name == "" {
+		name = h.config.Index
+	}
+
+	if isHidden(name) {
+		return "", false
+	}
+
+	if info, err := fs.Stat(h.fsys, name); err == nil && info.IsDir() {
+		name = path.Join(name, h.config.Index)
+	}
+
+	if _, err := fs.Stat(h.fsys, name); err != nil {
+		if !h.fallback(urlPath) {
+			return "", false
+		}
+
+		name = h.config.Index
+	}
+
+	return name, true
+}
+
+// fallback verifies if the path is served by the SPA index
+func (h *staticHandler) fallback(urlPath string) bool {
+	if !h.config.SPA || path.Ext(urlPath) != "" {
+		return false
+	}
+
+	for _, prefix := range h.config.Exclude {
+		if strings.HasPrefix(urlPath, prefix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// serve serves the file, or its compressed variant
+func (h *staticHandler) serve(w http.ResponseWriter, r *http.Request, name string) {
+	file, encoding := h.variant(r, name)
+
+	f, err := h.fsys.Open(file)
+
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	content, ok := f.(io.ReadSeeker)
+
+	if !ok {
+		data, err := ioutil.ReadAll(f)
+
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		content = bytes.NewReader(data)
+	}
+
+	etag, err := h.etag(file, info, content)
+
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	header := w.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", h.cacheControl(name))
+	header.Add("Vary", "Accept-Encoding")
+
+	if encoding != "" {
+		header.Set("Content-Encoding", encoding)
+
+		ctype := mime.TypeByExtension(path.Ext(name))
+
+		if ctype == "" {
+			ctype = "application/octet-stream"
+		}
+
+		header.Set("Content-Type", ctype)
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), content)
+}
+
+// variant gets the compressed variant accepted by the client, if available
+func (h *staticHandler) variant(r *http.Request, name string) (string, string) {
+	accept := r.Header.Get("Accept-Encoding")
+
+	for _, v := range []struct{ ext, encoding string }{{".br", "br"}, {".gz", "gzip"}} {
+		if !acceptsEncoding(accept, v.encoding) {
+			continue
+		}
+
+		if info, err := fs.Stat(h.fsys, name+v.ext); err == nil && !info.IsDir() {
+			return name + v.ext, v.encoding
+		}
+	}
+
+	return name, ""
+}
+
+// etag gets the strong ETag of the file content.
+// It is cached while the file keeps its size and modification time.
+func (h *staticHandler) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	h.mu.Lock()
+	cached, ok := h.etags[name]
+	h.mu.Unlock()
+
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.etag, nil
+	}
+
+	hash := sha256.New()
+
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+
+	h.mu.Lock()
+	h.etags[name] = staticETag{info.ModTime(), info.Size(), etag}
+	h.mu.Unlock()
+
+	return etag, nil
+}
+
+// cacheControl gets the Cache-Control of the file
+func (h *staticHandler) cacheControl(name string) string {
+	switch {
+	case path.Base(name) == h.config.Index:
+		return "no-cache"
+	case h.config.Immutable.MatchString(path.Base(name)):
+		return "public, max-age=31536000, immutable"
+	case h.config.MaxAge > 0:
+		return "public, max-age=" + strconv.FormatInt(int64(h.config.MaxAge/time.Second), 10)
+	}
+
+	return "no-cache"
+}
+
+// isHidden verifies if a segment of the name starts with a dot
+func isHidden(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// acceptsEncoding verifies if the Accept-Encoding accepts the encoding
+func acceptsEncoding(accept, encoding string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+
+		if strings.TrimSpace(fields[0]) != encoding {
+			continue
+		}
+
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,102 @@
+package knife
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func newStaticRouter() *Router {
+	fsys := fstest.MapFS{
+		"index.html":         {Data: []byte("<html>")},
+		"app.3f2a9c1b.js":    {Data: []byte("js")},
+		"app.3f2a9c1b.js.gz": {Data: []byte("gz")},
+		".env":               {Data: []byte("secret")},
+	}
+
+	r := NewRouter()
+	r.AddRoutes("v1", NewRouteFunc("ping", r.Router.GET, "/ping", func(resp Response, req *Request) (Response, error) {
+		return resp.NoContent()
+	}))
+	r.SetErrorHandler(NewProblemErrorHandler(false))
+	r.SetFallback(NewStaticHandler(fsys, StaticConfig{SPA: true, Exclude: []string{"/api/"}}))
+	r.Start()
+
+	return r
+}
+
+func TestStaticFallback(t *testing.T) {
+	r := newStaticRouter()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		encoding string
+		status   int
+		ctype    string
+		body     string
+	}{
+		{"index", "GET", "/", "", http.StatusOK, "text/html; charset=utf-8", "<html>"},
+		{"file", "GET", "/app.3f2a9c1b.js", "", http.StatusOK, "text/javascript; charset=utf-8", "js"},
+		{"gzip variant", "GET", "/app.3f2a9c1b.js", "gzip", http.StatusOK, "text/javascript; charset=utf-8", "gz"},
+		{"spa route", "GET", "/users/1", "", http.StatusOK, "text/html; charset=utf-8", "<html>"},
+		{"spa route head", "HEAD", "/users/1", "", http.StatusOK, "text/html; charset=utf-8", ""},
+		{"spa route post", "POST", "/users/1", "", http.StatusNotFound, ProblemContentType, ""},
+		{"excluded path", "GET", "/api/users", "", http.StatusNotFound, ProblemContentType, ""},
+		{"group path", "GET", "/v1/usres", "", http.StatusNotFound, ProblemContentType, ""},
+		{"missing file", "GET", "/logo.png", "", http.StatusNotFound, ProblemContentType, ""},
+		{"hidden file", "GET", "/.env", "", http.StatusNotFound, ProblemContentType, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+
+		if tt.encoding != "" {
+			req.Header.Set("Accept-Encoding", tt.encoding)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, w.Code)
+		}
+
+		if ctype := w.Header().Get("Content-Type"); ctype != tt.ctype {
+			t.Errorf("%s: expected Content-Type %s, got %s", tt.name, tt.ctype, ctype)
+		}
+
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: expected body %q, got %q", tt.name, tt.body, w.Body.String())
+		}
+	}
+}
+
+func TestStaticConditional(t *testing.T) {
+	r := newStaticRouter()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/app.3f2a9c1b.js", nil))
+
+	etag := w.Header().Get("ETag")
+
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=31536000, immutable" {
+		t.Errorf("expected an immutable Cache-Control, got %s", cc)
+	}
+
+	req := httptest.NewRequest("GET", "/app.3f2a9c1b.js", nil)
+	req.Header.Set("If-None-Match", etag)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", w.Code)
+	}
+}
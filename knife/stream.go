@@ -0,0 +1,196 @@
+package knife
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHeartbeat is the interval of the SSE heartbeats
+const DefaultHeartbeat = 15 * time.Second
+
+// StreamFunc writes a streaming body.
+// The flush sends the written data to the client.
+type StreamFunc func(w io.Writer, flush func()) error
+
+// Stream creates a response streaming the body.
+// The body is closed when it is an io.ReadCloser.
+func (r Response) Stream(body io.Reader) (Response, error) {
+	return r.StreamFunc(func(w io.Writer, flush func()) error {
+		if c, ok := body.(io.Closer); ok {
+			defer c.Close()
+		}
+
+		_, err := io.Copy(w, body)
+
+		return err
+	})
+}
+
+// StreamFunc creates a response streaming the body written by fn.
+// The status and headers are sent before fn is called, so its errors
+// are only logged.
+func (r Response) StreamFunc(fn StreamFunc) (Response, error) {
+	r.stream = fn
+	return r, nil
+}
+
+// writeStream writes the streaming body on w
+func writeStream(w http.ResponseWriter, fn StreamFunc) error {
+	flush := func() {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+
+	return fn(w, flush)
+}
+
+// Event represents a Server-Sent Event.
+// The Data is split in many data lines when it has line breaks.
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// EventStream sends the events of a SSE response.
+// It is safe to use from many goroutines.
+type EventStream struct {
+	mu          sync.Mutex
+	w           io.Writer
+	flush       func()
+	req         *http.Request
+	lastEventID string
+}
+
+// LastEventID gets the Last-Event-ID sent by a reconnecting client
+func (s *EventStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done is closed when the client disconnects
+func (s *EventStream) Done() <-chan struct{} {
+	return s.req.Context().Done()
+}
+
+// Send sends an event to the client.
+// It fails when the client is disconnected.
+func (s *EventStream) Send(e Event) error {
+	var b bytes.Buffer
+
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", oneLine(e.ID))
+	}
+
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", oneLine(e.Event))
+	}
+
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", e.Retry/time.Millisecond)
+	}
+
+	for _, line := range strings.Split(strings.Replace(e.Data, "\r\n", "\n", -1), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// comment sends a comment line, ignored by the clients
+func (s *EventStream) comment(c string) error {
+	return s.write(": " + oneLine(c) + "\n\n")
+}
+
+// write writes and flushes the data
+func (s *EventStream) write(data string) error {
+	if err := s.req.Context().Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := io.WriteString(s.w, data); err != nil {
+		return err
+	}
+
+	s.flush()
+
+	return nil
+}
+
+// oneLine removes the line breaks of a field value
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// SSE creates a text/event-stream response sending the events of fn.
+// A heartbeat comment is sent on each interval to keep the connection,
+// using the DefaultHeartbeat when heartbeat is not positive.
+// The fn should return when the stream is done, the client disconnects
+// or Send fails. The disconnection is not an error.
+func (r Response) SSE(req *Request, heartbeat time.Duration, fn func(*EventStream) error) (Response, error) {
+	if heartbeat <= 0 {
+		heartbeat = DefaultHeartbeat
+	}
+
+	r.SetContentType("text/event-stream; charset=utf-8")
+	r.Header().Set("Cache-Control", "no-cache")
+	r.Header().Set("X-Accel-Buffering", "no")
+
+	return r.StreamFunc(func(w io.Writer, flush func()) error {
+		s := &EventStream{
+			w:           w,
+			flush:       flush,
+			req:         req.Target(),
+			lastEventID: req.Target().Header.Get("Last-Event-ID"),
+		}
+
+		flush()
+
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ticker := time.NewTicker(heartbeat)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					if s.comment("heartbeat") != nil {
+						return
+					}
+				case <-done:
+					return
+				case <-s.Done():
+					return
+				}
+			}
+		}()
+
+		err := fn(s)
+
+		close(done)
+		wg.Wait()
+
+		if req.Context().Err() != nil {
+			return nil
+		}
+
+		return err
+	})
+}
@@ -0,0 +1,134 @@
+package knife
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tralus/koala/errors"
+)
+
+// URL builds the path of a route from its token and params.
+// The params are name/value pairs, like URL("users.show", "id", 42).
+// The values can be strings, integers, floats, booleans or fmt.Stringer.
+// It returns an IllegalArgumentError for missing or empty params, and an
+// IllegalStateError for unknown tokens or params and values of unsupported types.
+func (r *Router) URL(token string, params ...interface{}) (string, error) {
+	route, ok := r.route(token)
+
+	if !ok {
+		return "", newURLStateError("Route %s is not registered.", token)
+	}
+
+	if len(params)%2 != 0 {
+		return "", newURLStateError("Route %s got an odd number of params.", token)
+	}
+
+	values := make(map[string]string, len(params)/2)
+
+	for i := 0; i < len(params); i += 2 {
+		name, ok := params[i].(string)
+
+		if !ok {
+			return "", newURLStateError("Route %s got a param name that is not a string.", token)
+		}
+
+		v, err := formatURLParam(params[i+1])
+
+		if err != nil {
+			return "", newURLStateError("Route %s got an invalid param %s: %s", token, name, err)
+		}
+
+		values[name] = v
+	}
+
+	segments := strings.Split(route.Path, "/")
+
+	for i, s := range segments {
+		if len(s) == 0 || (s[0] != ':' && s[0] != '*') {
+			continue
+		}
+
+		name := s[1:]
+		v, ok := values[name]
+
+		if !ok || v == "" {
+			return "", newURLError("Route %s requires the param %s.", token, name)
+		}
+
+		delete(values, name)
+
+		if s[0] == '*' {
+			segments[i] = escapeCatchAll(v)
+		} else {
+			segments[i] = url.PathEscape(v)
+		}
+	}
+
+	for name := range values {
+		return "", newURLStateError("Route %s has no param %s.", token, name)
+	}
+
+	return strings.Join(segments, "/"), nil
+}
+
+// route gets the route registered for the token
+func (r *Router) route(token string) (*Route, bool) {
+	for _, routes := range r.routes {
+		for _, route := range routes {
+			if route.Token == token {
+				return route, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// newURLError creates an IllegalArgumentError for URL building
+func newURLError(format string, args ...interface{}) error {
+	return errors.NewIllegalArgumentError(errors.Errorf(format, args...))
+}
+
+// newURLStateError creates an IllegalStateError for the URL building
+// errors made by the code, like an unknown token
+func newURLStateError(format string, args ...interface{}) error {
+	return errors.NewIllegalStateError(errors.Errorf(format, args...))
+}
+
+// formatURLParam formats a param value for the path
+func formatURLParam(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case int:
+		return strconv.Itoa(t), nil
+	case int8, int16, int32, int64:
+		return fmt.Sprint(t), nil
+	case uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(t), nil
+	case float32:
+		return strconv.FormatFloat(float64(t), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case fmt.Stringer:
+		return t.String(), nil
+	}
+
+	return "", errors.Errorf("unsupported type %T", v)
+}
+
+// escapeCatchAll escapes each segment of a catch-all value.
+// The leading slash is removed, since the route path has one.
+func escapeCatchAll(v string) string {
+	parts := strings.Split(strings.TrimPrefix(v, "/"), "/")
+
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+
+	return strings.Join(parts, "/")
+}
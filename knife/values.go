@@ -0,0 +1,270 @@
+package knife
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/tralus/koala/errors"
+	"github.com/tralus/koala/validate"
+)
+
+// Values represents named values of a request, like the route params or the query.
+// The accessors return an IllegalArgumentError naming the value when it is
+// missing or invalid. The accessors with the "Or" suffix return the default
+// when the value is missing or empty, but still fail when it is invalid.
+type Values struct {
+	source string
+	data   url.Values
+}
+
+// NewValues creates a Values instance.
+// The source names the values on the error messages, like "query param".
+func NewValues(source string, data url.Values) Values {
+	return Values{source, data}
+}
+
+// NewRouteParams creates a RouteParams instance
+func NewRouteParams(ps httprouter.Params) RouteParams {
+	data := make(url.Values, len(ps))
+
+	for _, p := range ps {
+		data.Add(p.Key, p.Value)
+	}
+
+	return RouteParams{ps, NewValues("route param", data)}
+}
+
+// Query gets the query string values
+func (r Request) Query() Values {
+	return NewValues("query param", r.target.URL.Query())
+}
+
+// Has verifies if the value is present and not empty
+func (v Values) Has(name string) bool {
+	return v.data.Get(name) != ""
+}
+
+// String gets a required value
+func (v Values) String(name string) (string, error) {
+	s := v.data.Get(name)
+
+	if s == "" {
+		return "", v.missing(name)
+	}
+
+	return s, nil
+}
+
+// StringOr gets a value or the default
+func (v Values) StringOr(name string, def string) string {
+	if s := v.data.Get(name); s != "" {
+		return s
+	}
+
+	return def
+}
+
+// Int64 gets a required value as an int64
+func (v Values) Int64(name string) (int64, error) {
+	s, err := v.String(name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := strconv.ParseInt(s, 10, 64)
+
+	if err != nil {
+		return 0, v.invalid(name, "an integer")
+	}
+
+	return i, nil
+}
+
+// Int64Or gets a value as an int64 or the default
+func (v Values) Int64Or(name string, def int64) (int64, error) {
+	if !v.Has(name) {
+		return def, nil
+	}
+
+	return v.Int64(name)
+}
+
+// Uint gets a required value as an uint64
+func (v Values) Uint(name string) (uint64, error) {
+	s, err := v.String(name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	u, err := strconv.ParseUint(s, 10, 64)
+
+	if err != nil {
+		return 0, v.invalid(name, "a positive integer")
+	}
+
+	return u, nil
+}
+
+// UintOr gets a value as an uint64 or the default
+func (v Values) UintOr(name string, def uint64) (uint64, error) {
+	if !v.Has(name) {
+		return def, nil
+	}
+
+	return v.Uint(name)
+}
+
+// Bool gets a required value as a bool.
+// It accepts the values supported by strconv.ParseBool.
+func (v Values) Bool(name string) (bool, error) {
+	s, err := v.String(name)
+
+	if err != nil {
+		return false, err
+	}
+
+	b, err := strconv.ParseBool(s)
+
+	if err != nil {
+		return false, v.invalid(name, "a boolean")
+	}
+
+	return b, nil
+}
+
+// BoolOr gets a value as a bool or the default
+func (v Values) BoolOr(name string, def bool) (bool, error) {
+	if !v.Has(name) {
+		return def, nil
+	}
+
+	return v.Bool(name)
+}
+
+// Float gets a required value as a float64
+func (v Values) Float(name string) (float64, error) {
+	s, err := v.String(name)
+
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+
+	if err != nil {
+		return 0, v.invalid(name, "a number")
+	}
+
+	return f, nil
+}
+
+// FloatOr gets a value as a float64 or the default
+func (v Values) FloatOr(name string, def float64) (float64, error) {
+	if !v.Has(name) {
+		return def, nil
+	}
+
+	return v.Float(name)
+}
+
+// UUID gets a required value as an UUID in lower case
+func (v Values) UUID(name string) (string, error) {
+	s, err := v.String(name)
+
+	if err != nil {
+		return "", err
+	}
+
+	if !validate.IsUUID(s) {
+		return "", v.invalid(name, "an UUID")
+	}
+
+	return strings.ToLower(s), nil
+}
+
+// UUIDOr gets a value as an UUID or the default
+func (v Values) UUIDOr(name string, def string) (string, error) {
+	if !v.Has(name) {
+		return def, nil
+	}
+
+	return v.UUID(name)
+}
+
+// Time gets a required value as a time in the layout
+func (v Values) Time(name string, layout string) (time.Time, error) {
+	s, err := v.String(name)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := time.Parse(layout, s)
+
+	if err != nil {
+		return time.Time{}, v.invalid(name, "a time like "+layout)
+	}
+
+	return t, nil
+}
+
+// TimeOr gets a value as a time in the layout or the default
+func (v Values) TimeOr(name string, layout string, def time.Time) (time.Time, error) {
+	if !v.Has(name) {
+		return def, nil
+	}
+
+	return v.Time(name, layout)
+}
+
+// List gets the comma-separated items of a value.
+// Repeated values are joined, like "?id=1,2&id=3".
+// The items are trimmed and the empty items are dropped.
+func (v Values) List(name string) []string {
+	var items []string
+
+	for _, s := range v.data[name] {
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+
+	return items
+}
+
+// Int64List gets the comma-separated items of a value as int64
+func (v Values) Int64List(name string) ([]int64, error) {
+	items := v.List(name)
+	list := make([]int64, len(items))
+
+	for i, item := range items {
+		n, err := strconv.ParseInt(item, 10, 64)
+
+		if err != nil {
+			return nil, v.invalid(name, "a list of integers")
+		}
+
+		list[i] = n
+	}
+
+	return list, nil
+}
+
+// missing creates the error for a missing value
+func (v Values) missing(name string) error {
+	err := errors.Errorf("The %s %q is required.", v.source, name)
+	return errors.NewIllegalArgumentError(err)
+}
+
+// invalid creates the error for an invalid value
+func (v Values) invalid(name string, expected string) error {
+	err := errors.Errorf("The %s %q must be %s.", v.source, name, expected)
+	return errors.NewIllegalArgumentError(err)
+}
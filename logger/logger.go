@@ -0,0 +1,225 @@
+// Package logger provides a leveled logger with key/value fields.
+// It writes text for humans or JSON for log pipelines.
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level represents a log level
+type Level int
+
+// Log levels
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+// String gets the level name
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	}
+
+	return "level(" + strconv.Itoa(int(l)) + ")"
+}
+
+// Format represents an output format
+type Format int
+
+// Output formats
+const (
+	TextFormat Format = iota
+	JSONFormat
+)
+
+// TimeFormat is the layout of the log time
+const TimeFormat = time.RFC3339
+
+// Logger represents a leveled logger.
+// The loggers created by With share the output with their parent.
+type Logger struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	level  Level
+	format Format
+	fields []interface{}
+}
+
+// New creates a *Logger instance
+func New(out io.Writer, level Level, format Format) *Logger {
+	return &Logger{mu: &sync.Mutex{}, out: out, level: level, format: format}
+}
+
+// NewDefault creates a *Logger instance that writes to the stdout.
+// In debug mode, it writes text from the Debug level.
+// Otherwise, it writes JSON from the Info level.
+func NewDefault(debug bool) *Logger {
+	if debug {
+		return New(os.Stdout, DebugLevel, TextFormat)
+	}
+
+	return New(os.Stdout, InfoLevel, JSONFormat)
+}
+
+var std = New(os.Stdout, InfoLevel, TextFormat)
+
+// Default gets the logger used when none is injected
+func Default() *Logger {
+	return std
+}
+
+// Discard creates a *Logger instance that writes nothing
+func Discard() *Logger {
+	return New(ioutil.Discard, ErrorLevel+1, TextFormat)
+}
+
+// SetLevel sets the minimum level to write
+func (l *Logger) SetLevel(level Level) {
+	l.level = level
+}
+
+// Level gets the minimum level to write
+func (l *Logger) Level() Level {
+	return l.level
+}
+
+// Enabled verifies if the level is written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// With creates a child logger that adds the key/value fields to every entry
+func (l *Logger) With(kv ...interface{}) *Logger {
+	c := *l
+	c.fields = append(append([]interface{}{}, l.fields...), kv...)
+	return &c
+}
+
+// Debug writes a message on the Debug level
+func (l *Logger) Debug(msg string, kv ...interface{}) {
+	l.Log(DebugLevel, msg, kv...)
+}
+
+// Info writes a message on the Info level
+func (l *Logger) Info(msg string, kv ...interface{}) {
+	l.Log(InfoLevel, msg, kv...)
+}
+
+// Warn writes a message on the Warn level
+func (l *Logger) Warn(msg string, kv ...interface{}) {
+	l.Log(WarnLevel, msg, kv...)
+}
+
+// Error writes a message on the Error level
+func (l *Logger) Error(msg string, kv ...interface{}) {
+	l.Log(ErrorLevel, msg, kv...)
+}
+
+// Log writes a message with the key/value fields.
+// A key without value gets the "!MISSING" value.
+func (l *Logger) Log(level Level, msg string, kv ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	fields := append(append([]interface{}{}, l.fields...), kv...)
+
+	if len(fields)%2 != 0 {
+		fields = append(fields, "!MISSING")
+	}
+
+	var b bytes.Buffer
+
+	now := time.Now().Format(TimeFormat)
+
+	if l.format == JSONFormat {
+		writeJSON(&b, now, level, msg, fields)
+	} else {
+		writeText(&b, now, level, msg, fields)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.out.Write(b.Bytes())
+}
+
+// writeText writes an entry like: time LEVEL msg key=value
+func writeText(b *bytes.Buffer, now string, level Level, msg string, fields []interface{}) {
+	fmt.Fprintf(b, "%s %-5s %s", now, strings.ToUpper(level.String()), msg)
+
+	for i := 0; i < len(fields); i += 2 {
+		v := fmt.Sprint(value(fields[i+1]))
+
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = strconv.Quote(v)
+		}
+
+		fmt.Fprintf(b, " %s=%s", fmt.Sprint(fields[i]), v)
+	}
+
+	b.WriteByte('\n')
+}
+
+// writeJSON writes an entry as a JSON object in one line
+func writeJSON(b *bytes.Buffer, now string, level Level, msg string, fields []interface{}) {
+	b.WriteString(`{"time":`)
+	writeJSONValue(b, now)
+	b.WriteString(`,"level":`)
+	writeJSONValue(b, level.String())
+	b.WriteString(`,"msg":`)
+	writeJSONValue(b, msg)
+
+	for i := 0; i < len(fields); i += 2 {
+		b.WriteByte(',')
+		writeJSONValue(b, fmt.Sprint(fields[i]))
+		b.WriteByte(':')
+		writeJSONValue(b, value(fields[i+1]))
+	}
+
+	b.WriteString("}\n")
+}
+
+// writeJSONValue writes v as JSON or as a JSON string on failure
+func writeJSONValue(b *bytes.Buffer, v interface{}) {
+	bytes, err := json.Marshal(v)
+
+	if err != nil {
+		bytes, _ = json.Marshal(fmt.Sprint(v))
+	}
+
+	b.Write(bytes)
+}
+
+// value converts errors, durations and stringers to strings
+func value(v interface{}) interface{} {
+	switch t := v.(type) {
+	case error:
+		return t.Error()
+	case time.Duration:
+		return t.String()
+	case fmt.Stringer:
+		return t.String()
+	}
+
+	return v
+}
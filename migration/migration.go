@@ -2,6 +2,7 @@ package migration
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/rubenv/sql-migrate"
 )
@@ -38,6 +39,45 @@ func (m Migration) Down() (int, error) {
 	return migrate.Exec(m.db, m.dialect, m.migrations, migrate.Down)
 }
 
+// Status represents the status of a migration
+type Status struct {
+	ID        string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Status gets the status of each migration, sorted by id
+func (m Migration) Status() ([]Status, error) {
+	m.configureSQLMigrate()
+
+	migrations, err := m.migrations.FindMigrations()
+
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := migrate.GetMigrationRecords(m.db, m.dialect)
+
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]time.Time, len(records))
+
+	for _, r := range records {
+		applied[r.Id] = r.AppliedAt
+	}
+
+	status := make([]Status, len(migrations))
+
+	for i, mg := range migrations {
+		at, ok := applied[mg.Id]
+		status[i] = Status{mg.Id, ok, at}
+	}
+
+	return status, nil
+}
+
 func (m Migration) configureSQLMigrate() {
 	schema := "public"
 	table := "sql_migrations"
@@ -0,0 +1,213 @@
+package koala
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tralus/koala/errors"
+	"github.com/tralus/koala/health"
+)
+
+// Module phases
+const (
+	PhaseInit  = "init"
+	PhaseStart = "start"
+	PhaseStop  = "stop"
+)
+
+// Module defines the interface for modules.
+// Init prepares the module, Start runs it and Stop releases its resources.
+type Module interface {
+	Name() string
+
+	Init() error
+
+	Start() error
+
+	Stop(ctx context.Context) error
+}
+
+// DependentModule defines the optional interface for modules
+// that depend on other modules. The dependencies are started first.
+type DependentModule interface {
+	DependsOn() []string
+}
+
+// HealthModule defines the optional interface for modules
+// that register named health checks. The checks are registered after Init.
+type HealthModule interface {
+	HealthChecks() map[string]health.Checker
+}
+
+// BaseModule implements Module with no-op lifecycle methods.
+// It should be embedded on modules.
+type BaseModule struct {
+	name string
+	deps []string
+}
+
+// NewBaseModule creates a BaseModule instance
+func NewBaseModule(name string, deps ...string) BaseModule {
+	return BaseModule{name, deps}
+}
+
+// Name implements Module
+func (m BaseModule) Name() string {
+	return m.name
+}
+
+// DependsOn implements DependentModule
+func (m BaseModule) DependsOn() []string {
+	return m.deps
+}
+
+// Init implements Module
+func (m BaseModule) Init() error {
+	return nil
+}
+
+// Start implements Module
+func (m BaseModule) Start() error {
+	return nil
+}
+
+// Stop implements Module
+func (m BaseModule) Stop(ctx context.Context) error {
+	return nil
+}
+
+// ModuleError represents an error in a module phase
+type ModuleError struct {
+	errors.BaseError
+
+	Module string
+	Phase  string
+}
+
+// NewModuleError creates a ModuleError instance
+func NewModuleError(module, phase string, err error) error {
+	err = errors.Wrap(err, fmt.Sprintf("module %s failed on %s", module, phase))
+	return ModuleError{errors.NewBaseError(err), module, phase}
+}
+
+// IsModuleError verifies if error is a ModuleError
+func IsModuleError(err error) bool {
+	_, ok := errors.Cause(err).(ModuleError)
+	return ok
+}
+
+// ModuleStatus represents the status of a module on the startup
+type ModuleStatus struct {
+	Name     string
+	Phase    string
+	Duration time.Duration
+	Err      error
+}
+
+// StartupReport represents the startup status of the modules
+type StartupReport []ModuleStatus
+
+// Failed gets the status of the failed module
+func (r StartupReport) Failed() (ModuleStatus, bool) {
+	for _, s := range r {
+		if s.Err != nil {
+			return s, true
+		}
+	}
+
+	return ModuleStatus{}, false
+}
+
+// String formats the report as one line per module
+func (r StartupReport) String() string {
+	var b bytes.Buffer
+
+	for _, s := range r {
+		state := "ok"
+
+		if s.Err != nil {
+			state = "failed: " + s.Err.Error()
+		}
+
+		fmt.Fprintf(&b, "%s [%s] %s (%s)\n", s.Name, s.Phase, state, s.Duration)
+	}
+
+	return b.String()
+}
+
+// sortModules sorts the modules so that the dependencies come first.
+// The registration order is kept among independent modules.
+func sortModules(modules []Module) ([]Module, error) {
+	byName := make(map[string]Module, len(modules))
+
+	for _, m := range modules {
+		if _, ok := byName[m.Name()]; ok {
+			err := errors.Errorf("Module %s is registered many times.", m.Name())
+			return nil, errors.NewIllegalStateError(err)
+		}
+
+		byName[m.Name()] = m
+	}
+
+	for _, m := range modules {
+		for _, d := range dependencies(m) {
+			if _, ok := byName[d]; !ok {
+				err := errors.Errorf("Module %s depends on unknown module %s.", m.Name(), d)
+				return nil, errors.NewIllegalStateError(err)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int, len(modules))
+	sorted := make([]Module, 0, len(modules))
+
+	var visit func(m Module) error
+
+	visit = func(m Module) error {
+		switch state[m.Name()] {
+		case visited:
+			return nil
+		case visiting:
+			err := errors.Errorf("Module %s has a cyclic dependency.", m.Name())
+			return errors.NewIllegalStateError(err)
+		}
+
+		state[m.Name()] = visiting
+
+		for _, d := range dependencies(m) {
+			if err := visit(byName[d]); err != nil {
+				return err
+			}
+		}
+
+		state[m.Name()] = visited
+		sorted = append(sorted, m)
+
+		return nil
+	}
+
+	for _, m := range modules {
+		if err := visit(m); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
+
+// dependencies gets the module dependencies
+func dependencies(m Module) []string {
+	if d, ok := m.(DependentModule); ok {
+		return d.DependsOn()
+	}
+
+	return nil
+}
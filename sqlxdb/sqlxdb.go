@@ -3,6 +3,7 @@ package sqlxdb
 import (
 	"github.com/jmoiron/sqlx"
 	"github.com/tralus/koala/errors"
+	"github.com/tralus/koala/health"
 )
 
 // Config represents the database config
@@ -33,6 +34,11 @@ func Connect(c Config) (*sqlx.DB, error) {
 	return db, err
 }
 
+// HealthChecker creates a health checker that pings the database
+func HealthChecker(db *sqlx.DB) health.Checker {
+	return health.PingChecker(db)
+}
+
 // DatabaseError error type for database error
 type DatabaseError struct {
 	errors.BaseError
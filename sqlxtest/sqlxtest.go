@@ -2,10 +2,10 @@ package sqlxtest
 
 import (
 	"errors"
-	"fmt"
 	"net/url"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/tralus/koala/logger"
 	"github.com/tralus/koala/migration"
 	"github.com/tralus/koala/sqlxdb"
 )
@@ -174,6 +174,7 @@ func GetDatabaseNameFromDSN(dsn string) (string, error) {
 type Setup struct {
 	dbConfig sqlxdb.Config
 	dbSetter DatabaseSetter
+	logger   *logger.Logger
 }
 
 // NewSetup creates a Setup instance
@@ -184,7 +185,21 @@ func NewSetup(dbConfig sqlxdb.Config) (Setup, error) {
 		return Setup{}, err
 	}
 
-	return Setup{dbConfig, st}, nil
+	return Setup{dbConfig: dbConfig, dbSetter: st}, nil
+}
+
+// SetLogger sets the logger for the setup steps
+func (s *Setup) SetLogger(l *logger.Logger) {
+	s.logger = l
+}
+
+// log gets the setup logger or the default logger
+func (s Setup) log() *logger.Logger {
+	if s.logger == nil {
+		return logger.Default()
+	}
+
+	return s.logger
 }
 
 // Run runs the test setup
@@ -193,7 +208,7 @@ func (s Setup) Run() error {
 		return err
 	}
 
-	fmt.Println("Creating the test database...")
+	s.log().Info("Creating the test database...")
 
 	if err := s.dbSetter.CreateDatabase(); err != nil {
 		return err
@@ -213,7 +228,7 @@ func (s Setup) Run() error {
 		source,
 	)
 
-	fmt.Println("Applying migrations...")
+	s.log().Info("Applying migrations...")
 
 	i, err := migration.Up()
 
@@ -221,14 +236,14 @@ func (s Setup) Run() error {
 		return err
 	}
 
-	fmt.Printf("Number of migrations applied: %d.\n", i)
+	s.log().Info("Migrations applied", "count", i)
 
 	return nil
 }
 
 // Destroy clears all after the test setup
 func (s Setup) Destroy() error {
-	fmt.Println("Destroying test database...")
+	s.log().Info("Destroying test database...")
 
 	if err := s.dbSetter.DropDatabase(); err != nil {
 		return err
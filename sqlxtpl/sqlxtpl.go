@@ -1,10 +1,13 @@
 package sqlxtpl
 
 import (
+	stdcontext "context"
 	"database/sql"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/tralus/koala/context"
 	"github.com/tralus/koala/errors"
+	"github.com/tralus/koala/logger"
 	"github.com/tralus/koala/sqlxdb"
 
 	"gopkg.in/guregu/null.v3"
@@ -24,6 +27,11 @@ func NewEmptyResultDataError(err error) error {
 	return EmptyResultDataError{errors.NewBaseError(err)}
 }
 
+// NotFound implements errors.NotFounder
+func (e EmptyResultDataError) NotFound() bool {
+	return true
+}
+
 // IsEmptyResultDataError verifies if error is an EmptyResultDataError
 func IsEmptyResultDataError(err error) bool {
 	_, ok := errors.Cause(err).(EmptyResultDataError)
@@ -55,6 +63,81 @@ type SqlxTpl struct {
 	TransactedSQL
 
 	DB *sqlx.DB
+
+	logger    *logger.Logger
+	ctx       stdcontext.Context
+	requestID string
+}
+
+// WithContext creates a copy of the template running the queries with ctx,
+// usually the context of the request. The queries are prefixed with
+// a comment holding the request id of ctx, like "/* request_id=... */".
+func (s SqlxTpl) WithContext(ctx stdcontext.Context) SqlxTpl {
+	s.ctx = ctx
+	s.requestID = context.RequestID(ctx)
+
+	return s
+}
+
+// WithRequestID creates a copy of the template that prefixes the queries
+// with a comment holding the request id, like "/* request_id=... */".
+// Ids with unsafe chars are ignored. WithContext sets it from the request.
+func (s SqlxTpl) WithRequestID(id string) SqlxTpl {
+	if context.ValidRequestID(id) {
+		s.requestID = id
+	}
+
+	return s
+}
+
+// queryContext gets the context of the queries
+func (s SqlxTpl) queryContext() stdcontext.Context {
+	if s.ctx == nil {
+		return stdcontext.Background()
+	}
+
+	return s.ctx
+}
+
+// comment prefixes the query with the request id comment
+func (s SqlxTpl) comment(query string) string {
+	if s.requestID == "" {
+		return query
+	}
+
+	return "/* request_id=" + s.requestID + " */ " + query
+}
+
+// SetLogger sets the logger for the queries
+func (s *SqlxTpl) SetLogger(l *logger.Logger) {
+	s.logger = l
+}
+
+// log gets the template logger or the default logger
+func (s SqlxTpl) log() *logger.Logger {
+	l := s.logger
+
+	if l == nil {
+		l = logger.Default()
+	}
+
+	if s.requestID != "" {
+		return l.With("request_id", s.requestID)
+	}
+
+	return l
+}
+
+// logQuery logs the query on the Debug level and the database errors on the Error level.
+// It returns err, so it can wrap the returned errors.
+func (s SqlxTpl) logQuery(query string, err error) error {
+	if sqlxdb.IsDatabaseError(err) {
+		s.log().Error("Query failed", "query", query, "error", err)
+	} else {
+		s.log().Debug("Query", "query", query)
+	}
+
+	return err
 }
 
 // ParseRows is used as a callback function to parse query result
@@ -84,91 +167,91 @@ func processRows(rows *sqlx.Rows, err error, parse ParseRows) error {
 
 // NamedQuery executes a safe named query
 func (s SqlxTpl) NamedQuery(query string, arg interface{}, parse ParseRows) error {
-	rows, err := s.DB.NamedQuery(query, arg)
-	return processRows(rows, err, parse)
+	rows, err := s.DB.NamedQueryContext(s.queryContext(), s.comment(query), arg)
+	return s.logQuery(query, processRows(rows, err, parse))
 }
 
 // UnsafeNamedQuery executes an unsafe named query
 func (s SqlxTpl) UnsafeNamedQuery(query string, arg interface{}, parse ParseRows) error {
-	rows, err := s.DB.Unsafe().NamedQuery(query, arg)
-	return processRows(rows, err, parse)
+	rows, err := s.DB.Unsafe().NamedQueryContext(s.queryContext(), s.comment(query), arg)
+	return s.logQuery(query, processRows(rows, err, parse))
 }
 
 // Queryx executes a safe query
 func (s SqlxTpl) Queryx(query string, args []interface{}, parse ParseRows) error {
-	rows, err := s.DB.Queryx(query, args...)
-	return processRows(rows, err, parse)
+	rows, err := s.DB.QueryxContext(s.queryContext(), s.comment(query), args...)
+	return s.logQuery(query, processRows(rows, err, parse))
 }
 
 // UnsafeQueryx executes an unsafe query
 func (s SqlxTpl) UnsafeQueryx(query string, parse ParseRows, args ...interface{}) error {
-	rows, err := s.DB.Unsafe().Queryx(query, args...)
-	return processRows(rows, err, parse)
+	rows, err := s.DB.Unsafe().QueryxContext(s.queryContext(), s.comment(query), args...)
+	return s.logQuery(query, processRows(rows, err, parse))
 }
 
 // UnsafeSelect executes an unsafe select
 func (s SqlxTpl) UnsafeSelect(dest interface{}, query string, args ...interface{}) error {
-	err := s.DB.Unsafe().Select(dest, query, args...)
+	err := s.DB.Unsafe().SelectContext(s.queryContext(), dest, s.comment(query), args...)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return NewEmptyResultDataError(
 				errors.Wrap(err, emptyResultData))
 		}
-		return sqlxdb.NewDatabaseError(errors.Wrap(err, dbError))
+		return s.logQuery(query, sqlxdb.NewDatabaseError(errors.Wrap(err, dbError)))
 	}
 
-	return nil
+	return s.logQuery(query, nil)
 }
 
 // Select executes a safe select
 func (s SqlxTpl) Select(dest interface{}, query string, args ...interface{}) error {
-	err := s.DB.Select(dest, query, args...)
+	err := s.DB.SelectContext(s.queryContext(), dest, s.comment(query), args...)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return NewEmptyResultDataError(
 				errors.Wrap(err, emptyResultData))
 		}
-		return sqlxdb.NewDatabaseError(errors.Wrap(err, dbError))
+		return s.logQuery(query, sqlxdb.NewDatabaseError(errors.Wrap(err, dbError)))
 	}
 
-	return nil
+	return s.logQuery(query, nil)
 }
 
 // UnsafeGet executes unsafe get on the database connection
 func (s SqlxTpl) UnsafeGet(dest interface{}, query string, args ...interface{}) error {
-	err := s.DB.Unsafe().Get(dest, query, args...)
+	err := s.DB.Unsafe().GetContext(s.queryContext(), dest, s.comment(query), args...)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return NewEmptyResultDataError(
 				errors.Wrap(err, emptyResultData))
 		}
-		return sqlxdb.NewDatabaseError(errors.Wrap(err, dbError))
+		return s.logQuery(query, sqlxdb.NewDatabaseError(errors.Wrap(err, dbError)))
 	}
 
-	return nil
+	return s.logQuery(query, nil)
 }
 
 // Get executes safe get on the database connection
 func (s SqlxTpl) Get(dest interface{}, query string, args ...interface{}) error {
-	err := s.DB.Get(dest, query, args...)
+	err := s.DB.GetContext(s.queryContext(), dest, s.comment(query), args...)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return NewEmptyResultDataError(
 				errors.Wrap(err, emptyResultData))
 		}
-		return sqlxdb.NewDatabaseError(errors.Wrap(err, dbError))
+		return s.logQuery(query, sqlxdb.NewDatabaseError(errors.Wrap(err, dbError)))
 	}
 
-	return nil
+	return s.logQuery(query, nil)
 }
 
 // NewSqlxTpl creates a SqlxTpl instance
 func NewSqlxTpl(db *sqlx.DB) SqlxTpl {
-	return SqlxTpl{TransactedSQL{}, db}
+	return SqlxTpl{DB: db}
 }
 
 // TxDo executes a callback function with a shared transaction
@@ -253,15 +336,17 @@ func (s SqlxTpl) NamedExec(query string, arg interface{}) (result sql.Result, er
 	tx := s.Tx()
 
 	if tx != nil {
-		result, err = tx.NamedExec(query, arg)
+		result, err = tx.NamedExecContext(s.queryContext(), s.comment(query), arg)
 	} else {
-		result, err = s.DB.NamedExec(query, arg)
+		result, err = s.DB.NamedExecContext(s.queryContext(), s.comment(query), arg)
 	}
 
 	if err != nil {
-		return nil, sqlxdb.NewDatabaseError(errors.Wrap(err, dbError))
+		return nil, s.logQuery(query, sqlxdb.NewDatabaseError(errors.Wrap(err, dbError)))
 	}
 
+	s.logQuery(query, nil)
+
 	return
 }
 
@@ -272,14 +357,14 @@ func (s SqlxTpl) TxNamedExec(tx *sqlx.Tx, query string, arg interface{}) (sql.Re
 			errors.New("Tx is not a valid instance."))
 	}
 
-	sqlResult, err := tx.NamedExec(query, arg)
+	sqlResult, err := tx.NamedExecContext(s.queryContext(), s.comment(query), arg)
 
 	if err != nil {
-		return nil, sqlxdb.NewDatabaseError(
-			errors.Wrap(err, dbError))
+		return nil, s.logQuery(query, sqlxdb.NewDatabaseError(
+			errors.Wrap(err, dbError)))
 	}
 
-	return sqlResult, nil
+	return sqlResult, s.logQuery(query, nil)
 }
 
 // NullInt returns a invalid null.Int when i is zero
@@ -7,7 +7,10 @@ import (
 	"github.com/tralus/koala/context"
 )
 
-const keyTokenContext = "koala.token.0"
+// contextKey is the type of the context keys of this package
+type contextKey string
+
+const keyTokenContext contextKey = "koala.token.0"
 
 // Token represents a Token
 type Token struct {
@@ -19,9 +22,18 @@ func New(value string) Token {
 	return Token{value}
 }
 
-// ToContext puts a Token instance to the request context
+// WithToken puts a Token instance to the request context.
+// The returned request must be passed to the next handlers.
+func WithToken(r *http.Request, t Token) *http.Request {
+	return context.With(r, keyTokenContext, t)
+}
+
+// ToContext puts a Token instance to the request context,
+// replacing the request in place.
+//
+// Deprecated: use WithToken and pass the returned request to the next handlers.
 func ToContext(r *http.Request, t Token) {
-	context.Add(r, keyTokenContext, t)
+	*r = *WithToken(r, t)
 }
 
 // FromContext gets a Token instance from the request context
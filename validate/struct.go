@@ -0,0 +1,564 @@
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tralus/koala/errors"
+)
+
+// TagName is the struct tag read by Struct
+const TagName = "validate"
+
+// Rule validates a value with the rule param.
+// It returns an error with a message like "must be ..." when the value fails,
+// or an IllegalStateError when the param is invalid.
+// The value is never a pointer: nil pointers only reach the required rule.
+type Rule func(v reflect.Value, param string) error
+
+// Registry holds the named rules
+type Registry struct {
+	mu    sync.RWMutex
+	rules map[string]Rule
+}
+
+// NewRegistry creates a *Registry instance with the built-in rules:
+// required, min, max, len, email, url, uuid, oneof and regex.
+func NewRegistry() *Registry {
+	r := &Registry{rules: make(map[string]Rule)}
+
+	r.Register("required", required)
+	r.Register("min", minRule)
+	r.Register("max", maxRule)
+	r.Register("len", length)
+	r.Register("email", email)
+	r.Register("url", isURL)
+	r.Register("uuid", uuid)
+	r.Register("oneof", oneOf)
+	r.Register("regex", matchRegex)
+
+	return r
+}
+
+// DefaultRegistry is the registry used by Struct and Register
+var DefaultRegistry = NewRegistry()
+
+// Register registers a named rule on the DefaultRegistry
+func Register(name string, rule Rule) {
+	DefaultRegistry.Register(name, rule)
+}
+
+// Struct validates the struct v with the DefaultRegistry
+func Struct(v interface{}) error {
+	return DefaultRegistry.Struct(v)
+}
+
+// Register registers a named rule.
+// A rule with the same name is replaced.
+func (r *Registry) Register(name string, rule Rule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rules[name] = rule
+}
+
+// rule gets a rule by name
+func (r *Registry) rule(name string) (Rule, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rule, ok := r.rules[name]
+	return rule, ok
+}
+
+// Struct validates the struct v, or a pointer to it, by the validate tags.
+// The tag holds comma-separated rules, like `validate:"required,min=3"`.
+// The regex rule takes the rest of the tag, so it must be the last one.
+// The omitempty rule skips the next rules when the value is zero.
+// It recurses into nested structs, slices and maps, and returns the
+// failures as FieldErrors within an IllegalArgumentError.
+// It returns an IllegalStateError when a tag uses an unknown rule
+// or an invalid param.
+func (r *Registry) Struct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return newArgumentError(errors.New("Validation requires a not nil struct."))
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return newArgumentError(errors.Errorf("Validation requires a struct, got %s.", rv.Kind()))
+	}
+
+	fes, err := r.validateStruct(rv, "")
+
+	if err != nil {
+		return err
+	}
+
+	if len(fes) > 0 {
+		return NewFieldErrors(fes...)
+	}
+
+	return nil
+}
+
+// validateStruct validates the fields of the struct
+func (r *Registry) validateStruct(v reflect.Value, path string) (FieldErrors, error) {
+	var fes FieldErrors
+
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		fv := v.Field(i)
+
+		if sf.PkgPath != "" {
+			// only the fields promoted by the unexported embedded structs are validated
+			if sf.Anonymous && indirect(fv).Kind() == reflect.Struct {
+				nfes, err := r.validateNested(fv, path)
+
+				if err != nil {
+					return nil, err
+				}
+
+				fes = append(fes, nfes...)
+			}
+			continue
+		}
+
+		fpath := path
+
+		if !sf.Anonymous {
+			fpath = joinPath(path, fieldName(sf))
+		}
+
+		rfes, err := r.validateRules(fv, fpath, sf.Tag.Get(TagName))
+
+		if err != nil {
+			return nil, err
+		}
+
+		nfes, err := r.validateNested(fv, fpath)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fes = append(fes, rfes...)
+		fes = append(fes, nfes...)
+	}
+
+	return fes, nil
+}
+
+// validateRules applies the tag rules on the value
+func (r *Registry) validateRules(v reflect.Value, path string, tag string) (FieldErrors, error) {
+	for _, rp := range parseTag(tag) {
+		name, param := rp[0], rp[1]
+
+		if name == "omitempty" {
+			if isZero(v) {
+				return nil, nil
+			}
+			continue
+		}
+
+		rule, ok := r.rule(name)
+
+		if !ok {
+			err := errors.Errorf("The rule %s of the field %s is unknown.", name, path)
+			return nil, errors.NewIllegalStateError(err)
+		}
+
+		iv := indirect(v)
+
+		if !iv.IsValid() && name != "required" {
+			continue // nil pointers only fail the required rule
+		}
+
+		if name == "required" {
+			iv = v
+		}
+
+		if err := rule(iv, param); err != nil {
+			if errors.IsIllegalStateError(err) {
+				return nil, err
+			}
+
+			return FieldErrors{{Field: path, Rule: name, Message: err.Error()}}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// validateNested validates the structs within the value
+func (r *Registry) validateNested(v reflect.Value, path string) (FieldErrors, error) {
+	v = indirect(v)
+
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			return nil, nil
+		}
+
+		return r.validateStruct(v, path)
+
+	case reflect.Slice, reflect.Array:
+		var fes FieldErrors
+
+		for i := 0; i < v.Len(); i++ {
+			nfes, err := r.validateNested(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+
+			if err != nil {
+				return nil, err
+			}
+
+			fes = append(fes, nfes...)
+		}
+
+		return fes, nil
+
+	case reflect.Map:
+		var fes FieldErrors
+
+		for _, k := range v.MapKeys() {
+			nfes, err := r.validateNested(v.MapIndex(k), fmt.Sprintf("%s[%v]", path, k))
+
+			if err != nil {
+				return nil, err
+			}
+
+			fes = append(fes, nfes...)
+		}
+
+		return fes, nil
+	}
+
+	return nil, nil
+}
+
+// parseTag splits the tag in name/param pairs
+func parseTag(tag string) [][2]string {
+	var rules [][2]string
+
+	for tag != "" {
+		var item string
+
+		if strings.HasPrefix(tag, "regex=") {
+			item, tag = tag, ""
+		} else if i := strings.Index(tag, ","); i >= 0 {
+			item, tag = tag[:i], tag[i+1:]
+		} else {
+			item, tag = tag, ""
+		}
+
+		item = strings.TrimSpace(item)
+
+		if item == "" {
+			continue
+		}
+
+		name, param := item, ""
+
+		if i := strings.Index(item, "="); i >= 0 {
+			name, param = item[:i], item[i+1:]
+		}
+
+		rules = append(rules, [2]string{name, param})
+	}
+
+	return rules
+}
+
+// fieldName gets the JSON name of the field, or the Go name
+func fieldName(sf reflect.StructField) string {
+	if name := strings.Split(sf.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+
+	return sf.Name
+}
+
+// joinPath joins the field path with the field name
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// indirect dereferences the pointers and interfaces.
+// It returns an invalid value for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// isZero verifies if the value is the zero value of its type
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	}
+
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// size gets the size compared by min, max and len.
+// It is the value for numbers, the number of chars for strings
+// and the length for slices and maps.
+func size(v reflect.Value) (float64, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), nil
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	}
+
+	return 0, errors.Errorf("has the unsupported type %s", v.Type())
+}
+
+// sizeParam parses the param of min, max and len.
+// It returns an IllegalStateError when the param is not a number.
+func sizeParam(rule, param string) (float64, error) {
+	n, err := strconv.ParseFloat(param, 64)
+
+	if err != nil {
+		err = errors.Errorf("The %s rule requires a number, got %q.", rule, param)
+		return 0, errors.NewIllegalStateError(err)
+	}
+
+	return n, nil
+}
+
+// unit gets the unit of the size for the messages
+func unit(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return " chars"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return " items"
+	}
+
+	return ""
+}
+
+// required fails on zero values
+func required(v reflect.Value, param string) error {
+	if isZero(v) {
+		return errors.New("is required")
+	}
+
+	return nil
+}
+
+// minRule fails when the size is less than the param
+func minRule(v reflect.Value, param string) error {
+	n, err := sizeParam("min", param)
+
+	if err != nil {
+		return err
+	}
+
+	s, err := size(v)
+
+	if err != nil {
+		return err
+	}
+
+	if s < n {
+		return errors.Errorf("must have at least %s%s", param, unit(v))
+	}
+
+	return nil
+}
+
+// maxRule fails when the size is greater than the param
+func maxRule(v reflect.Value, param string) error {
+	n, err := sizeParam("max", param)
+
+	if err != nil {
+		return err
+	}
+
+	s, err := size(v)
+
+	if err != nil {
+		return err
+	}
+
+	if s > n {
+		return errors.Errorf("must have at most %s%s", param, unit(v))
+	}
+
+	return nil
+}
+
+// length fails when the size is not the param
+func length(v reflect.Value, param string) error {
+	n, err := sizeParam("len", param)
+
+	if err != nil {
+		return err
+	}
+
+	s, err := size(v)
+
+	if err != nil {
+		return err
+	}
+
+	if s != n {
+		return errors.Errorf("must have exactly %s%s", param, unit(v))
+	}
+
+	return nil
+}
+
+// email fails when the string is not an email address
+func email(v reflect.Value, param string) error {
+	if v.Kind() != reflect.String {
+		return errors.Errorf("has the unsupported type %s", v.Type())
+	}
+
+	a, err := mail.ParseAddress(v.String())
+
+	if err != nil || a.Address != v.String() {
+		return errors.New("must be a valid email")
+	}
+
+	return nil
+}
+
+// isURL fails when the string is not an absolute URL
+func isURL(v reflect.Value, param string) error {
+	if v.Kind() != reflect.String {
+		return errors.Errorf("has the unsupported type %s", v.Type())
+	}
+
+	u, err := url.Parse(v.String())
+
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return errors.New("must be a valid URL")
+	}
+
+	return nil
+}
+
+// uuidRegex validates the canonical UUID form
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// IsUUID verifies if s is an UUID in the canonical form
+func IsUUID(s string) bool {
+	return uuidRegex.MatchString(s)
+}
+
+// uuid fails when the string is not an UUID
+func uuid(v reflect.Value, param string) error {
+	if v.Kind() != reflect.String || !IsUUID(v.String()) {
+		return errors.New("must be a valid UUID")
+	}
+
+	return nil
+}
+
+// oneOf fails when the value is not one of the space-separated param items
+func oneOf(v reflect.Value, param string) error {
+	s := fmt.Sprint(v.Interface())
+
+	for _, item := range strings.Fields(param) {
+		if s == item {
+			return nil
+		}
+	}
+
+	return errors.Errorf("must be one of: %s", strings.Join(strings.Fields(param), ", "))
+}
+
+// regexCache holds the compiled regex params
+var regexCache = struct {
+	sync.Mutex
+	m map[string]*regexp.Regexp
+}{m: make(map[string]*regexp.Regexp)}
+
+// matchRegex fails when the string does not match the param.
+// It returns an IllegalStateError when the param is not a valid regex.
+func matchRegex(v reflect.Value, param string) error {
+	x, err := compileRegex(param)
+
+	if err != nil {
+		return err
+	}
+
+	if v.Kind() != reflect.String || !x.MatchString(v.String()) {
+		return errors.Errorf("must match %s", param)
+	}
+
+	return nil
+}
+
+// compileRegex gets the compiled regex of the param from the cache
+func compileRegex(param string) (*regexp.Regexp, error) {
+	regexCache.Lock()
+	x, ok := regexCache.m[param]
+	regexCache.Unlock()
+
+	if ok {
+		return x, nil
+	}
+
+	x, err := regexp.Compile(param)
+
+	if err != nil {
+		return nil, errors.NewIllegalStateError(errors.Wrap(err, "The regex rule has an invalid param."))
+	}
+
+	regexCache.Lock()
+	regexCache.m[param] = x
+	regexCache.Unlock()
+
+	return x, nil
+}
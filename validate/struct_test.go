@@ -0,0 +1,153 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/tralus/koala/errors"
+)
+
+type address struct {
+	Zip string `json:"zip" validate:"required,len=5"`
+}
+
+type user struct {
+	Name    string            `json:"name" validate:"required,min=2,max=10"`
+	Email   string            `json:"email" validate:"omitempty,email"`
+	Site    string            `json:"site" validate:"omitempty,url"`
+	ID      string            `json:"id" validate:"omitempty,uuid"`
+	Role    string            `json:"role" validate:"omitempty,oneof=admin user"`
+	Code    string            `json:"code" validate:"omitempty,regex=^[A-Z]{3}$"`
+	Age     int               `json:"age" validate:"min=18"`
+	Address *address          `json:"address"`
+	Items   []address         `json:"items"`
+	Tags    map[string]string `json:"tags" validate:"max=2"`
+}
+
+type code string
+
+type page struct {
+	Page int `json:"page" validate:"min=1"`
+}
+
+type embedded struct {
+	code `validate:"oneof=a b"`
+	page
+}
+
+type badRegex struct {
+	Code string `validate:"regex=[A-Z"`
+}
+
+type badMin struct {
+	Code string `validate:"min=abc"`
+}
+
+type badLen struct {
+	Code string `validate:"len=x"`
+}
+
+type unknownRule struct {
+	Code string `validate:"required,even"`
+}
+
+func validUser() user {
+	return user{Name: "Ana", Age: 18}
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name   string
+		edit   func(u *user)
+		fields []string
+	}{
+		{"valid", func(u *user) {}, nil},
+		{"required", func(u *user) { u.Name = "" }, []string{"name"}},
+		{"min string", func(u *user) { u.Name = "A" }, []string{"name"}},
+		{"max string", func(u *user) { u.Name = "Anastasia Maria" }, []string{"name"}},
+		{"min number", func(u *user) { u.Age = 17 }, []string{"age"}},
+		{"max map", func(u *user) { u.Tags = map[string]string{"a": "", "b": "", "c": ""} }, []string{"tags"}},
+		{"email", func(u *user) { u.Email = "ana" }, []string{"email"}},
+		{"valid email", func(u *user) { u.Email = "ana@example.com" }, nil},
+		{"url", func(u *user) { u.Site = "example" }, []string{"site"}},
+		{"uuid", func(u *user) { u.ID = "123" }, []string{"id"}},
+		{"valid uuid", func(u *user) { u.ID = "4b3e2a5c-1d2f-4e6a-8b9c-0d1e2f3a4b5c" }, nil},
+		{"oneof", func(u *user) { u.Role = "root" }, []string{"role"}},
+		{"regex", func(u *user) { u.Code = "abc" }, []string{"code"}},
+		{"valid regex", func(u *user) { u.Code = "ABC" }, nil},
+		{"nested pointer", func(u *user) { u.Address = &address{Zip: "123"} }, []string{"address.zip"}},
+		{"nested slice", func(u *user) { u.Items = []address{{Zip: "12345"}, {}} }, []string{"items[1].zip"}},
+	}
+
+	for _, tt := range tests {
+		u := validUser()
+		tt.edit(&u)
+
+		err := Struct(&u)
+
+		if tt.fields == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+
+		fes, ok := GetFieldErrors(err)
+
+		if !ok {
+			t.Errorf("%s: expected field errors, got %v", tt.name, err)
+			continue
+		}
+
+		if len(fes) != len(tt.fields) {
+			t.Errorf("%s: expected %d field errors, got %v", tt.name, len(tt.fields), fes)
+			continue
+		}
+
+		for i, field := range tt.fields {
+			if fes[i].Field != field {
+				t.Errorf("%s: expected field %s, got %s", tt.name, field, fes[i].Field)
+			}
+		}
+	}
+}
+
+func TestStructEmbedded(t *testing.T) {
+	fes, ok := GetFieldErrors(Struct(embedded{code: "z"}))
+
+	if !ok || len(fes) != 1 || fes[0].Field != "page" {
+		t.Errorf("expected only the page field error, got %v", fes)
+	}
+}
+
+func TestStructInvalidTag(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"bad regex", badRegex{Code: "A"}},
+		{"bad min", badMin{Code: "A"}},
+		{"bad len", badLen{Code: "A"}},
+		{"unknown rule", unknownRule{Code: "A"}},
+	}
+
+	for _, tt := range tests {
+		// the second call verifies the regex cache is not left locked
+		for i := 0; i < 2; i++ {
+			if err := Struct(tt.v); !errors.IsIllegalStateError(err) {
+				t.Errorf("%s: expected an IllegalStateError, got %v", tt.name, err)
+			}
+		}
+	}
+}
+
+func TestStructArgument(t *testing.T) {
+	var u *user
+
+	if err := Struct(u); !errors.IsIllegalArgumentError(err) {
+		t.Errorf("expected an IllegalArgumentError for nil, got %v", err)
+	}
+
+	if err := Struct("user"); !errors.IsIllegalArgumentError(err) {
+		t.Errorf("expected an IllegalArgumentError for a string, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package validate
 
 import (
 	"reflect"
+	"strings"
 
 	"github.com/tralus/koala/errors"
 )
@@ -10,6 +11,46 @@ func newArgumentError(err error) error {
 	return errors.NewIllegalArgumentError(err)
 }
 
+// FieldError represents a failing rule for a field.
+// The field is the path of the field, like "address.zip" or "items[0].name".
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// FieldErrors represents the failing fields
+type FieldErrors []FieldError
+
+// Error implements error.Error
+func (e FieldErrors) Error() string {
+	msgs := make([]string, len(e))
+
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// NewFieldErrors creates an IllegalArgumentError holding the field errors
+func NewFieldErrors(fe ...FieldError) error {
+	return newArgumentError(FieldErrors(fe))
+}
+
+// GetFieldErrors gets the field errors held by an IllegalArgumentError
+func GetFieldErrors(err error) (FieldErrors, bool) {
+	iae, ok := errors.Cause(err).(errors.IllegalArgumentError)
+
+	if !ok {
+		return nil, false
+	}
+
+	fe, ok := errors.Cause(iae.Origin()).(FieldErrors)
+
+	return fe, ok
+}
+
 // NotZero verifies if the v is a zero value on Go
 func NotZero(v interface{}) error {
 	st := reflect.ValueOf(v)